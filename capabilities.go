@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// providerEndpoints are the admin API endpoints used by the provider. GetCurrentAdminTokenInfo is left
+// out as the scope is only checked once it succeeded.
+var providerEndpoints = []string{
+	"GetClusterHealth", "GetClusterStatus",
+	"ListBuckets", "GetBucketInfo", "CreateBucket", "UpdateBucket", "DeleteBucket",
+	"AddBucketAlias", "RemoveBucketAlias",
+	"ListKeys", "GetKeyInfo", "CreateKey", "ImportKey", "UpdateKey", "DeleteKey",
+	"AllowBucketKey", "DenyBucketKey",
+}
+
+// Capabilities describes what the Garage cluster and admin token in use support.
+// Fields are left empty when they could not be detected.
+type Capabilities struct {
+	// AdminAPIVersion is the admin API version the provider talks to, e.g. v2
+	AdminAPIVersion string
+	// GarageVersion is the lowest Garage version running on the cluster's nodes
+	GarageVersion string
+	// TokenScope lists the admin API endpoints the token may call, "*" meaning all of them
+	TokenScope []string
+}
+
+// AllowsEndpoint reports whether the admin token may call an endpoint. An unknown scope allows everything.
+func (c *Capabilities) AllowsEndpoint(endpoint string) bool {
+	if c.TokenScope == nil {
+		return true
+	}
+
+	return slices.Contains(c.TokenScope, "*") || slices.Contains(c.TokenScope, endpoint)
+}
+
+// requireEndpoints returns an actionable error if the admin token may not call all of the given endpoints
+func (c *GarageClient) requireEndpoints(feature string, endpoints ...string) error {
+	var missing []string
+
+	for _, endpoint := range endpoints {
+		if !c.Capabilities.AllowsEndpoint(endpoint) {
+			missing = append(missing, endpoint)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s requires the admin token to be allowed to call %s; add them to the token scope",
+			feature, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// requireGarageVersion returns an actionable error if a node of the cluster runs a Garage version older
+// than minimum. An unknown version, e.g. when skip_health_check is set, is assumed to be recent enough.
+func (c *GarageClient) requireGarageVersion(feature, minimum string) error {
+	current, err := parseGarageVersion(c.Capabilities.GarageVersion)
+	if err != nil {
+		return nil
+	}
+
+	if current.LessThan(version.Must(version.NewVersion(minimum))) {
+		return fmt.Errorf("%s requires Garage v%s or later on every node, but a node of the cluster runs %s; upgrade it first",
+			feature, minimum, c.Capabilities.GarageVersion)
+	}
+
+	return nil
+}
+
+// detectCapabilities checks that the admin API is reachable with the configured token and
+// records the cluster version and token scope on the client
+func (c *GarageClient) detectCapabilities(ctx context.Context) diag.Diagnostics {
+	cfg := c.Client.GetConfig()
+	address := fmt.Sprintf("%s://%s", cfg.Scheme, cfg.Host)
+
+	// An unreachable host is reported right away instead of after every retry
+	_, resp, err := c.Client.ClusterAPI.GetClusterHealth(withoutDialRetries(ctx)).Execute()
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+
+	if err != nil {
+		switch {
+		case resp == nil:
+			return diag.Errorf("unable to reach the Garage admin API at %s: %s. Check the scheme and host settings and that the admin API is enabled (api_bind_addr)", address, err)
+		case resp.StatusCode == http.StatusUnauthorized:
+			return diag.Errorf("the Garage admin API at %s rejected the admin token. Check the token or token_file settings", address)
+		case resp.StatusCode == http.StatusForbidden:
+			return diag.Errorf("the admin token is not allowed to call GetClusterHealth on %s. Add it to the token scope", address)
+		case resp.StatusCode == http.StatusNotFound:
+			return diag.Errorf("the Garage admin API at %s does not serve the v2 API. This provider requires Garage v2.0.0 or later", address)
+		default:
+			return diag.FromErr(fmt.Errorf("failed to check Garage cluster health at %s: %w", address, err))
+		}
+	}
+
+	c.Capabilities.AdminAPIVersion = "v2"
+
+	var diags diag.Diagnostics
+
+	token, resp, err := c.Client.AdminAPITokenAPI.GetCurrentAdminTokenInfo(ctx).Execute()
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+
+	if err == nil {
+		if token.Expired {
+			return diag.Errorf("the admin token %q has expired", token.Name)
+		}
+
+		c.Capabilities.TokenScope = token.Scope
+
+		var missing []string
+
+		for _, endpoint := range providerEndpoints {
+			if !c.Capabilities.AllowsEndpoint(endpoint) {
+				missing = append(missing, endpoint)
+			}
+		}
+
+		if len(missing) > 0 {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Admin token has a limited scope",
+				Detail: fmt.Sprintf("The admin token %q is not allowed to call %s. Operations using these endpoints will fail.",
+					token.Name, strings.Join(missing, ", ")),
+			})
+		}
+	} else {
+		tflog.Debug(ctx, "Could not read admin token scope", map[string]interface{}{"error": err.Error()})
+	}
+
+	status, resp, err := c.Client.ClusterAPI.GetClusterStatus(ctx).Execute()
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+
+	if err == nil {
+		c.Capabilities.GarageVersion = lowestGarageVersion(status.Nodes)
+	} else {
+		tflog.Debug(ctx, "Could not detect Garage version", map[string]interface{}{"error": err.Error()})
+	}
+
+	tflog.Info(ctx, "Connected to Garage", map[string]interface{}{
+		"admin_api_version": c.Capabilities.AdminAPIVersion,
+		"garage_version":    c.Capabilities.GarageVersion,
+	})
+
+	return diags
+}
+
+// lowestGarageVersion returns the oldest Garage version among the nodes that are up,
+// as features are only usable once every node supports them
+func lowestGarageVersion(nodes []garage.NodeResp) string {
+	lowest := ""
+
+	var lowestVersion *version.Version
+
+	for i := range nodes {
+		if !nodes[i].IsUp {
+			continue
+		}
+
+		raw := nodes[i].GetGarageVersion()
+
+		v, err := parseGarageVersion(raw)
+		if err != nil {
+			continue
+		}
+
+		if lowestVersion == nil || v.LessThan(lowestVersion) {
+			lowest = raw
+			lowestVersion = v
+		}
+	}
+
+	return lowest
+}
+
+// parseGarageVersion parses versions as reported by Garage, such as "v2.1.0" or "git:v2.1.0-12-gabcdef"
+func parseGarageVersion(raw string) (*version.Version, error) {
+	start := strings.IndexAny(raw, "0123456789")
+	if start < 0 {
+		return nil, fmt.Errorf("no version number in %q", raw)
+	}
+
+	return version.NewVersion(raw[start:])
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequireEndpoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		scope      []string
+		endpoints  []string
+		wantErr    bool
+		wantDetail string
+	}{
+		{name: "unknown scope", scope: nil, endpoints: []string{"CreateBucket"}},
+		{name: "all endpoints", scope: []string{"*"}, endpoints: []string{"CreateBucket", "DeleteBucket"}},
+		{name: "listed endpoints", scope: []string{"CreateBucket", "DeleteBucket"}, endpoints: []string{"CreateBucket", "DeleteBucket"}},
+		{name: "no endpoints", scope: []string{"ListBuckets"}},
+		{
+			name:       "missing endpoints",
+			scope:      []string{"CreateBucket"},
+			endpoints:  []string{"CreateBucket", "UpdateBucket", "AddBucketAlias"},
+			wantErr:    true,
+			wantDetail: "allowed to call UpdateBucket, AddBucketAlias;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &GarageClient{Capabilities: Capabilities{TokenScope: tt.scope}}
+
+			err := client.requireEndpoints("Creating a bucket", tt.endpoints...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("requireEndpoints() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			if err != nil && !strings.Contains(err.Error(), tt.wantDetail) {
+				t.Errorf("requireEndpoints() error = %q, want it to contain %q", err, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestRequireGarageVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		minimum string
+		wantErr bool
+	}{
+		{name: "unknown version", version: "", minimum: "2.0.0"},
+		{name: "unparsable version", version: "dev", minimum: "2.0.0"},
+		{name: "same version", version: "v2.0.0", minimum: "2.0.0"},
+		{name: "newer version", version: "v2.1.0", minimum: "2.0.0"},
+		{name: "git build", version: "git:v2.0.0-12-gabcdef", minimum: "0.9.0"},
+		{name: "older version", version: "v1.1.0", minimum: "2.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &GarageClient{Capabilities: Capabilities{GarageVersion: tt.version}}
+
+			err := client.requireGarageVersion("Key expiration", tt.minimum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("requireGarageVersion() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			if err != nil && !strings.Contains(err.Error(), tt.version) {
+				t.Errorf("requireGarageVersion() error = %q, want it to name version %q", err, tt.version)
+			}
+		})
+	}
+}
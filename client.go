@@ -1,22 +1,151 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
 )
 
 type GarageClient struct {
-	Client *garage.APIClient
+	Client       *garage.APIClient
+	S3           *S3Client
+	Capabilities Capabilities
 }
 
-func NewGarageClient(scheme, host, token string) (*GarageClient, error) {
+// ClientConfig holds the provider settings used to build a GarageClient.
+type ClientConfig struct {
+	Scheme string
+	Host   string
+	Token  string
+	TLS    TLSConfig
+	Retry  RetryConfig
+	S3     S3Config
+}
+
+// TLSConfig holds the TLS settings shared by the admin and S3 API clients.
+type TLSConfig struct {
+	// CACertPEM is a PEM bundle of additional CAs to trust
+	CACertPEM []byte
+	// ClientCertPEM and ClientKeyPEM are presented to servers requiring mutual TLS
+	ClientCertPEM      []byte
+	ClientKeyPEM       []byte
+	InsecureSkipVerify bool
+}
+
+// S3Config holds the provider settings used to reach the Garage S3 API.
+type S3Config struct {
+	// Endpoint is the base URL of the S3 API, e.g. https://s3.example.com
+	Endpoint        string
+	Region          string
+	ForcePathStyle  bool
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+func NewGarageClient(config ClientConfig) (*GarageClient, error) {
+	transport, err := newHTTPTransport(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Transport: newRetryTransport(transport, config.Retry)}
+
 	cfg := garage.NewConfiguration()
-	cfg.Scheme = scheme
-	cfg.Host = host
-	cfg.DefaultHeader["Authorization"] = fmt.Sprintf("Bearer %s", token)
+	cfg.Scheme = config.Scheme
+	cfg.Host = config.Host
+	cfg.DefaultHeader["Authorization"] = fmt.Sprintf("Bearer %s", config.Token)
+	cfg.HTTPClient = httpClient
 
 	client := garage.NewAPIClient(cfg)
 
-	return &GarageClient{Client: client}, nil
+	s3Config := config.S3
+
+	region := s3Config.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	s3Client := &S3Client{
+		HTTPClient:      httpClient,
+		Region:          region,
+		ForcePathStyle:  s3Config.ForcePathStyle,
+		AccessKeyID:     s3Config.AccessKeyID,
+		SecretAccessKey: s3Config.SecretAccessKey,
+	}
+
+	if s3Config.Endpoint != "" {
+		endpoint, err := url.Parse(s3Config.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 endpoint URL: %w", err)
+		}
+
+		if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint URL %q: expected http(s)://host[:port][/path]", s3Config.Endpoint)
+		}
+
+		s3Client.Scheme = endpoint.Scheme
+		s3Client.Host = endpoint.Host
+		s3Client.BasePath = s3EscapePath(strings.TrimSuffix(endpoint.Path, "/"))
+	} else {
+		// Fall back to the default Garage S3 port on the admin API host
+		s3Client.Scheme = config.Scheme
+		s3Client.Host = replacePort(config.Host, 3900)
+	}
+
+	return &GarageClient{Client: client, S3: s3Client}, nil
+}
+
+// newHTTPTransport builds the transport used for both the admin and S3 APIs
+func newHTTPTransport(tlsConfig TLSConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify, //nolint:gosec // explicitly requested with insecure_skip_verify
+	}
+
+	if len(tlsConfig.CACertPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(tlsConfig.CACertPEM) {
+			return nil, fmt.Errorf("no valid PEM certificates found in the CA certificate bundle")
+		}
+
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	if len(tlsConfig.ClientCertPEM) > 0 || len(tlsConfig.ClientKeyPEM) > 0 {
+		cert, err := tls.X509KeyPair(tlsConfig.ClientCertPEM, tlsConfig.ClientKeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return transport, nil
+}
+
+// replacePort replaces the port in a host string (e.g., "127.0.0.1:3903" -> "127.0.0.1:3900").
+// It is only used to guess the S3 API address when no s3_endpoint url is configured, which
+// assumes the S3 API listens on the same host and scheme as the admin API.
+func replacePort(host string, newPort int) string {
+	// Simple implementation - if host contains a port, replace it
+	// Otherwise, append the new port
+	for i := len(host) - 1; i >= 0; i-- {
+		if host[i] == ':' {
+			return host[:i+1] + strconv.Itoa(newPort)
+		}
+	}
+
+	return host + ":" + strconv.Itoa(newPort)
 }
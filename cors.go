@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// CORSConfiguration represents an S3 bucket CORS configuration.
+type CORSConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Rules   []CORSRule `xml:"CORSRule"`
+}
+
+type CORSRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader,omitempty"`
+	ExposeHeaders  []string `xml:"ExposeHeader,omitempty"`
+	MaxAgeSeconds  *int     `xml:"MaxAgeSeconds,omitempty"`
+}
+
+func expandCORSRules(raw []interface{}) []CORSRule {
+	rules := make([]CORSRule, 0, len(raw))
+
+	for _, v := range raw {
+		if v == nil {
+			continue
+		}
+
+		block := v.(map[string]interface{})
+		rule := CORSRule{
+			ID:             block["id"].(string),
+			AllowedOrigins: expandStringSet(block["allowed_origins"].(*schema.Set)),
+			AllowedMethods: expandStringSet(block["allowed_methods"].(*schema.Set)),
+			AllowedHeaders: expandStringSet(block["allowed_headers"].(*schema.Set)),
+			ExposeHeaders:  expandStringSet(block["expose_headers"].(*schema.Set)),
+		}
+
+		if maxAge := block["max_age_seconds"].(int); maxAge > 0 {
+			rule.MaxAgeSeconds = &maxAge
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+func flattenCORSRules(rules []CORSRule) []interface{} {
+	result := make([]interface{}, 0, len(rules))
+
+	for _, rule := range rules {
+		maxAge := 0
+		if rule.MaxAgeSeconds != nil {
+			maxAge = *rule.MaxAgeSeconds
+		}
+
+		result = append(result, map[string]interface{}{
+			"id":              rule.ID,
+			"allowed_origins": rule.AllowedOrigins,
+			"allowed_methods": rule.AllowedMethods,
+			"allowed_headers": rule.AllowedHeaders,
+			"expose_headers":  rule.ExposeHeaders,
+			"max_age_seconds": maxAge,
+		})
+	}
+
+	return result
+}
+
+func expandStringSet(set *schema.Set) []string {
+	result := make([]string, 0, set.Len())
+
+	for _, v := range set.List() {
+		result = append(result, v.(string))
+	}
+
+	return result
+}
+
+// setBucketCORSConfiguration replaces the CORS rules of a bucket using the S3-compatible API
+func setBucketCORSConfiguration(ctx context.Context, client *GarageClient, bucketID string, rules []CORSRule) error {
+	xmlData, err := xml.MarshalIndent(CORSConfiguration{Rules: rules}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal CORS config: %w", err)
+	}
+
+	return withBucketS3Session(ctx, client, bucketID, func(s *s3Session) error {
+		headers := map[string]string{"Content-Type": "application/xml"}
+
+		resp, err := s.do(ctx, http.MethodPut, "", url.Values{"cors": {""}}, xmlData, headers)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if resp.Body != nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			return s3ResponseError(resp)
+		}
+
+		return nil
+	})
+}
+
+// getBucketCORSConfiguration retrieves the CORS rules of a bucket, none if it has no CORS configuration
+func getBucketCORSConfiguration(ctx context.Context, client *GarageClient, bucketID string) ([]CORSRule, error) {
+	var corsConfig CORSConfiguration
+
+	err := withBucketS3Session(ctx, client, bucketID, func(s *s3Session) error {
+		resp, err := s.do(ctx, http.MethodGet, "", url.Values{"cors": {""}}, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if resp.Body != nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		if resp.StatusCode == http.StatusNotFound {
+			return nil // No CORS configuration set
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return s3ResponseError(resp)
+		}
+
+		if err := xml.NewDecoder(resp.Body).Decode(&corsConfig); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return corsConfig.Rules, nil
+}
+
+// deleteBucketCORSConfiguration removes the CORS configuration from a bucket
+func deleteBucketCORSConfiguration(ctx context.Context, client *GarageClient, bucketID string) error {
+	return withBucketS3Session(ctx, client, bucketID, func(s *s3Session) error {
+		resp, err := s.do(ctx, http.MethodDelete, "", url.Values{"cors": {""}}, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if resp.Body != nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+			return s3ResponseError(resp)
+		}
+
+		return nil
+	})
+}
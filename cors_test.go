@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestCORSRulesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []interface{}
+		want  []CORSRule
+	}{
+		{
+			name: "required attributes only",
+			rules: []interface{}{
+				map[string]interface{}{
+					"allowed_origins": []interface{}{"*"},
+					"allowed_methods": []interface{}{"GET"},
+				},
+			},
+			want: []CORSRule{
+				{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowedHeaders: []string{}, ExposeHeaders: []string{}},
+			},
+		},
+		{
+			name: "every attribute",
+			rules: []interface{}{
+				map[string]interface{}{
+					"id":              "uploads",
+					"allowed_origins": []interface{}{"https://example.com"},
+					"allowed_methods": []interface{}{"PUT", "POST"},
+					"allowed_headers": []interface{}{"Content-Type"},
+					"expose_headers":  []interface{}{"ETag"},
+					"max_age_seconds": 3600,
+				},
+				map[string]interface{}{
+					"id":              "downloads",
+					"allowed_origins": []interface{}{"*"},
+					"allowed_methods": []interface{}{"GET", "HEAD"},
+				},
+			},
+			want: []CORSRule{
+				{
+					ID:             "uploads",
+					AllowedOrigins: []string{"https://example.com"},
+					AllowedMethods: []string{"POST", "PUT"},
+					AllowedHeaders: []string{"Content-Type"},
+					ExposeHeaders:  []string{"ETag"},
+					MaxAgeSeconds:  ptr(3600),
+				},
+				{
+					ID:             "downloads",
+					AllowedOrigins: []string{"*"},
+					AllowedMethods: []string{"GET", "HEAD"},
+					AllowedHeaders: []string{},
+					ExposeHeaders:  []string{},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, resourceGarageBucketCORSConfiguration().Schema, map[string]interface{}{
+				"bucket_id": "bucket",
+				"cors_rule": tt.rules,
+			})
+
+			rules := sortedCORSRules(expandCORSRules(d.Get("cors_rule").([]interface{})))
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Fatalf("expandCORSRules() = %#v, want %#v", rules, tt.want)
+			}
+
+			if err := d.Set("cors_rule", flattenCORSRules(rules)); err != nil {
+				t.Fatal(err)
+			}
+
+			roundTrip := sortedCORSRules(expandCORSRules(d.Get("cors_rule").([]interface{})))
+			if !reflect.DeepEqual(roundTrip, tt.want) {
+				t.Errorf("expandCORSRules(flattenCORSRules()) = %#v, want %#v", roundTrip, tt.want)
+			}
+		})
+	}
+}
+
+// sortedCORSRules sorts the values of the rules, which come from sets in no particular order
+func sortedCORSRules(rules []CORSRule) []CORSRule {
+	for _, rule := range rules {
+		slices.Sort(rule.AllowedOrigins)
+		slices.Sort(rule.AllowedMethods)
+		slices.Sort(rule.AllowedHeaders)
+		slices.Sort(rule.ExposeHeaders)
+	}
+
+	return rules
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceGarageBucket() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceGarageBucketRead,
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"id", "global_alias", "local_alias"},
+				Description:  "The bucket ID to look up",
+			},
+			"global_alias": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"id", "global_alias", "local_alias"},
+				Description:  "A global alias of the bucket to look up",
+			},
+			"local_alias": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"id", "global_alias", "local_alias"},
+				RequiredWith: []string{"access_key_id"},
+				Description:  "An alias of the bucket local to access_key_id",
+			},
+			"access_key_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"local_alias"},
+				Description:  "The access key owning local_alias",
+			},
+			"global_aliases": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "All global aliases of the bucket",
+			},
+			"local_aliases": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "All aliases of the bucket local to an access key",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"access_key_id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The access key the alias belongs to",
+						},
+						"alias": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The alias",
+						},
+					},
+				},
+			},
+			"bytes": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Total number of bytes used by objects in this bucket",
+			},
+			"objects": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Number of objects in this bucket",
+			},
+			"unfinished_uploads": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Number of unfinished uploads in this bucket",
+			},
+			"max_size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Maximum size quota for this bucket, 0 if unlimited",
+			},
+			"max_objects": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "Maximum number of objects quota for this bucket, 0 if unlimited",
+			},
+			"website_access_enabled": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether website access is enabled for this bucket",
+			},
+			"website_access_index_document": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Which document is served as index page for this bucket",
+			},
+			"website_access_error_document": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Which document is served as error page for this bucket",
+			},
+			"keys": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The keys having permissions on this bucket",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"access_key_id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The access key ID",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the key",
+						},
+						"read": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the key can read from the bucket",
+						},
+						"write": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the key can write to the bucket",
+						},
+						"owner": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the key owns the bucket",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceGarageBucketRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+
+	endpoints := []string{"GetBucketInfo"}
+	if d.Get("local_alias").(string) != "" {
+		endpoints = append(endpoints, "GetKeyInfo")
+	}
+
+	if err := client.requireEndpoints("Reading the garage_bucket data source", endpoints...); err != nil {
+		return diag.FromErr(err)
+	}
+
+	request := client.Client.BucketAPI.GetBucketInfo(ctx)
+	description := ""
+
+	switch {
+	case d.Get("global_alias").(string) != "":
+		globalAlias := d.Get("global_alias").(string)
+		request = request.GlobalAlias(globalAlias)
+		description = fmt.Sprintf("with global alias %q", globalAlias)
+	case d.Get("local_alias").(string) != "":
+		localAlias := d.Get("local_alias").(string)
+		keyID := d.Get("access_key_id").(string)
+
+		bucketID, err := findBucketIDByLocalAlias(ctx, client, keyID, localAlias)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		if bucketID == "" {
+			return diag.Errorf("key %s has no bucket with local alias %q", keyID, localAlias)
+		}
+
+		request = request.Id(bucketID)
+		description = fmt.Sprintf("with local alias %q of key %s", localAlias, keyID)
+	default:
+		request = request.Id(d.Get("id").(string))
+		description = d.Get("id").(string)
+	}
+
+	bucket, resp, err := request.Execute()
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return diag.Errorf("bucket %s not found", description)
+		}
+
+		return diag.FromErr(fmt.Errorf("failed to read bucket: %w", err))
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	d.SetId(bucket.Id)
+
+	quotas := bucket.GetQuotas()
+	websiteConfig := bucket.GetWebsiteConfig()
+
+	values := map[string]interface{}{
+		"id":                            bucket.Id,
+		"global_aliases":                bucket.GlobalAliases,
+		"local_aliases":                 flattenBucketLocalAliases(bucket.Keys),
+		"bytes":                         bucket.Bytes,
+		"objects":                       bucket.Objects,
+		"unfinished_uploads":            bucket.UnfinishedUploads,
+		"max_size":                      quotas.GetMaxSize(),
+		"max_objects":                   quotas.GetMaxObjects(),
+		"website_access_enabled":        bucket.WebsiteAccess,
+		"website_access_index_document": websiteConfig.GetIndexDocument(),
+		"website_access_error_document": websiteConfig.GetErrorDocument(),
+		"keys":                          flattenBucketKeys(bucket.Keys),
+	}
+
+	for name, value := range values {
+		if err := d.Set(name, value); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	return nil
+}
+
+// findBucketIDByLocalAlias resolves an alias local to an access key into a bucket ID, or "" if the key has no such alias
+func findBucketIDByLocalAlias(ctx context.Context, client *GarageClient, keyID, alias string) (string, error) {
+	key, resp, err := client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(keyID).Execute()
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", fmt.Errorf("key %s not found", keyID)
+		}
+
+		return "", fmt.Errorf("failed to read key: %w", err)
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	for _, bucket := range key.Buckets {
+		if slices.Contains(bucket.LocalAliases, alias) {
+			return bucket.Id, nil
+		}
+	}
+
+	return "", nil
+}
+
+func flattenBucketLocalAliases(keys []garage.GetBucketInfoKey) []interface{} {
+	result := make([]interface{}, 0)
+
+	for _, key := range keys {
+		for _, alias := range key.BucketLocalAliases {
+			result = append(result, map[string]interface{}{
+				"access_key_id": key.AccessKeyId,
+				"alias":         alias,
+			})
+		}
+	}
+
+	return result
+}
+
+func flattenBucketKeys(keys []garage.GetBucketInfoKey) []interface{} {
+	result := make([]interface{}, 0, len(keys))
+
+	for _, key := range keys {
+		result = append(result, map[string]interface{}{
+			"access_key_id": key.AccessKeyId,
+			"name":          key.Name,
+			"read":          key.Permissions.GetRead(),
+			"write":         key.Permissions.GetWrite(),
+			"owner":         key.Permissions.GetOwner(),
+		})
+	}
+
+	return result
+}
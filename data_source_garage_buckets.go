@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceGarageBuckets() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceGarageBucketsRead,
+		Schema: map[string]*schema.Schema{
+			"alias_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return buckets with a global alias starting with this prefix",
+			},
+			"alias_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+				Description:  "Only return buckets with a global alias matching this regular expression",
+			},
+			"has_website": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only return buckets with (true) or without (false) website access enabled. This reads every bucket, which is slower on large clusters",
+			},
+			"ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The IDs of the matching buckets",
+			},
+			"buckets": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The matching buckets",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The bucket ID",
+						},
+						"created": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "When the bucket was created (RFC3339)",
+						},
+						"global_aliases": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Global aliases of the bucket",
+						},
+						"local_aliases": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Description: "Aliases of the bucket local to an access key",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"access_key_id": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "The access key the alias belongs to",
+									},
+									"alias": {
+										Type:        schema.TypeString,
+										Computed:    true,
+										Description: "The alias",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceGarageBucketsRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+
+	aliasPrefix := d.Get("alias_prefix").(string)
+
+	var aliasRegex *regexp.Regexp
+	if v, ok := d.GetOk("alias_regex"); ok {
+		aliasRegex = regexp.MustCompile(v.(string))
+	}
+
+	// has_website is a tri-state filter, only apply it when it is set
+	hasWebsite := d.GetRawConfig().GetAttr("has_website")
+
+	endpoints := []string{"ListBuckets"}
+	if !hasWebsite.IsNull() {
+		endpoints = append(endpoints, "GetBucketInfo")
+	}
+
+	if err := client.requireEndpoints("Reading the garage_buckets data source", endpoints...); err != nil {
+		return diag.FromErr(err)
+	}
+
+	buckets, resp, err := client.Client.BucketAPI.ListBuckets(ctx).Execute()
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to list buckets: %w", err))
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	ids := make([]string, 0, len(buckets))
+	result := make([]interface{}, 0, len(buckets))
+
+	for _, bucket := range buckets {
+		if aliasPrefix != "" && !slices.ContainsFunc(bucket.GlobalAliases, func(alias string) bool { return strings.HasPrefix(alias, aliasPrefix) }) {
+			continue
+		}
+
+		if aliasRegex != nil && !slices.ContainsFunc(bucket.GlobalAliases, aliasRegex.MatchString) {
+			continue
+		}
+
+		if !hasWebsite.IsNull() {
+			websiteAccess, err := bucketWebsiteAccess(ctx, client, bucket.Id)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+
+			if websiteAccess != hasWebsite.True() {
+				continue
+			}
+		}
+
+		localAliases := make([]interface{}, 0, len(bucket.LocalAliases))
+		for _, alias := range bucket.LocalAliases {
+			localAliases = append(localAliases, map[string]interface{}{
+				"access_key_id": alias.AccessKeyId,
+				"alias":         alias.Alias,
+			})
+		}
+
+		ids = append(ids, bucket.Id)
+		result = append(result, map[string]interface{}{
+			"id":             bucket.Id,
+			"created":        bucket.Created.UTC().Format(time.RFC3339),
+			"global_aliases": bucket.GlobalAliases,
+			"local_aliases":  localAliases,
+		})
+	}
+
+	d.SetId(listItemID(ids))
+
+	if err := d.Set("ids", ids); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("buckets", result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func bucketWebsiteAccess(ctx context.Context, client *GarageClient, bucketID string) (bool, error) {
+	bucket, resp, err := client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			// Deleted since it was listed
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to read bucket %s: %w", bucketID, err)
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	return bucket.WebsiteAccess, nil
+}
+
+// listItemID builds a stable data source ID from the IDs of the listed items
+func listItemID(ids []string) string {
+	return strconv.Itoa(schema.HashString(strings.Join(ids, ",")))
+}
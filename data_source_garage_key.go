@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceGarageKey() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceGarageKeyRead,
+		Schema: map[string]*schema.Schema{
+			"access_key_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"access_key_id", "name"},
+				Description:  "The access key ID to look up",
+			},
+			"name": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ExactlyOneOf: []string{"access_key_id", "name"},
+				Description:  "The name of the key to look up. It must match exactly one key",
+			},
+			"created": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "When the key was created (RFC3339)",
+			},
+			"expiration": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "When the key expires (RFC3339), empty if it never expires",
+			},
+			"expired": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the key has expired",
+			},
+			"allow_create_bucket": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the key is allowed to create buckets",
+			},
+			"buckets": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The buckets this key has permissions on",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"bucket_id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The bucket ID",
+						},
+						"global_aliases": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Global aliases of the bucket",
+						},
+						"local_aliases": {
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Aliases of the bucket local to this key",
+						},
+						"read": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the key can read from the bucket",
+						},
+						"write": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the key can write to the bucket",
+						},
+						"owner": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the key owns the bucket",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceGarageKeyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+
+	keyID := d.Get("access_key_id").(string)
+
+	endpoints := []string{"GetKeyInfo"}
+	if keyID == "" {
+		endpoints = append(endpoints, "ListKeys")
+	}
+
+	if err := client.requireEndpoints("Reading the garage_key data source", endpoints...); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if keyID == "" {
+		var err error
+
+		keyID, err = findKeyIDByName(ctx, client, d.Get("name").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	key, resp, err := client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(keyID).Execute()
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return diag.Errorf("key %s not found", keyID)
+		}
+
+		return diag.FromErr(fmt.Errorf("failed to read key: %w", err))
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	d.SetId(key.AccessKeyId)
+
+	if err := d.Set("access_key_id", key.AccessKeyId); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("name", key.Name); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("created", formatOptionalTime(key.GetCreatedOk())); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("expiration", formatOptionalTime(key.GetExpirationOk())); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("expired", key.Expired); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("allow_create_bucket", key.Permissions.GetCreateBucket()); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("buckets", flattenKeyBuckets(key.Buckets)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+// findKeyIDByName returns the ID of the only key with the given name
+func findKeyIDByName(ctx context.Context, client *GarageClient, name string) (string, error) {
+	keys, resp, err := client.Client.AccessKeyAPI.ListKeys(ctx).Execute()
+	if err != nil {
+		return "", fmt.Errorf("failed to list keys: %w", err)
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	var ids []string
+
+	for _, key := range keys {
+		if key.Name == name {
+			ids = append(ids, key.Id)
+		}
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("no key named %q found", name)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%d keys are named %q (%s), use access_key_id to select one", len(ids), name, strings.Join(ids, ", "))
+	}
+}
+
+func flattenKeyBuckets(buckets []garage.KeyInfoBucketResponse) []interface{} {
+	result := make([]interface{}, 0, len(buckets))
+
+	for _, bucket := range buckets {
+		result = append(result, map[string]interface{}{
+			"bucket_id":      bucket.Id,
+			"global_aliases": bucket.GlobalAliases,
+			"local_aliases":  bucket.LocalAliases,
+			"read":           bucket.Permissions.GetRead(),
+			"write":          bucket.Permissions.GetWrite(),
+			"owner":          bucket.Permissions.GetOwner(),
+		})
+	}
+
+	return result
+}
+
+// formatOptionalTime formats a timestamp returned by the admin API as RFC3339, or "" if unset
+func formatOptionalTime(t *time.Time, ok bool) string {
+	if !ok || t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
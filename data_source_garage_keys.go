@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceGarageKeys() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceGarageKeysRead,
+		Schema: map[string]*schema.Schema{
+			"name_prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return keys with a name starting with this prefix",
+			},
+			"name_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringIsValidRegExp,
+				Description:  "Only return keys with a name matching this regular expression",
+			},
+			"expired": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Only return expired (true) or valid (false) keys",
+			},
+			"ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The access key IDs of the matching keys",
+			},
+			"keys": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The matching keys",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"access_key_id": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The access key ID",
+						},
+						"name": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "The name of the key",
+						},
+						"created": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "When the key was created (RFC3339)",
+						},
+						"expiration": {
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "When the key expires (RFC3339), empty if it never expires",
+						},
+						"expired": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Whether the key has expired",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceGarageKeysRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+
+	namePrefix := d.Get("name_prefix").(string)
+
+	var nameRegex *regexp.Regexp
+	if v, ok := d.GetOk("name_regex"); ok {
+		nameRegex = regexp.MustCompile(v.(string))
+	}
+
+	// expired is a tri-state filter, only apply it when it is set
+	expired := d.GetRawConfig().GetAttr("expired")
+
+	if err := client.requireEndpoints("Reading the garage_keys data source", "ListKeys"); err != nil {
+		return diag.FromErr(err)
+	}
+
+	keys, resp, err := client.Client.AccessKeyAPI.ListKeys(ctx).Execute()
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to list keys: %w", err))
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	ids := make([]string, 0, len(keys))
+	result := make([]interface{}, 0, len(keys))
+
+	for _, key := range keys {
+		if namePrefix != "" && !strings.HasPrefix(key.Name, namePrefix) {
+			continue
+		}
+
+		if nameRegex != nil && !nameRegex.MatchString(key.Name) {
+			continue
+		}
+
+		if !expired.IsNull() && key.Expired != expired.True() {
+			continue
+		}
+
+		ids = append(ids, key.Id)
+		result = append(result, map[string]interface{}{
+			"access_key_id": key.Id,
+			"name":          key.Name,
+			"created":       formatOptionalTime(key.GetCreatedOk()),
+			"expiration":    formatOptionalTime(key.GetExpirationOk()),
+			"expired":       key.Expired,
+		})
+	}
+
+	d.SetId(listItemID(ids))
+
+	if err := d.Set("ids", ids); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("keys", result); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	defaultEphemeralKeyName = "terraform-ephemeral"
+	defaultEphemeralKeyTTL  = time.Hour
+
+	// ephemeralKeyPrivateKey stores the ID of a key created by Open, so that Close deletes it
+	ephemeralKeyPrivateKey = "created_access_key_id"
+)
+
+// garageKeyEphemeralResource creates a key for the duration of a Terraform run, or reads the secret of an
+// existing key, without storing anything in state
+type garageKeyEphemeralResource struct {
+	client *GarageClient
+}
+
+var (
+	_ ephemeral.EphemeralResourceWithConfigure      = &garageKeyEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose          = &garageKeyEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithValidateConfig = &garageKeyEphemeralResource{}
+)
+
+type garageKeyEphemeralModel struct {
+	AccessKeyID     types.String                    `tfsdk:"access_key_id"`
+	Name            types.String                    `tfsdk:"name"`
+	TTL             types.String                    `tfsdk:"ttl"`
+	Bucket          []garageKeyEphemeralBucketModel `tfsdk:"bucket"`
+	SecretAccessKey types.String                    `tfsdk:"secret_access_key"`
+	Expiration      types.String                    `tfsdk:"expiration"`
+}
+
+type garageKeyEphemeralBucketModel struct {
+	BucketID types.String `tfsdk:"bucket_id"`
+	Read     types.Bool   `tfsdk:"read"`
+	Write    types.Bool   `tfsdk:"write"`
+	Owner    types.Bool   `tfsdk:"owner"`
+}
+
+func NewGarageKeyEphemeralResource() ephemeral.EphemeralResource {
+	return &garageKeyEphemeralResource{}
+}
+
+func (r *garageKeyEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_key"
+}
+
+func (r *garageKeyEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Credentials that are never stored in state. A key is created when Terraform opens the resource and deleted when it closes it, " +
+			"unless access_key_id is set, in which case the secret of that existing key is read",
+		Attributes: map[string]schema.Attribute{
+			"access_key_id": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The access key ID of an existing key to read. If unset, a key is created for the duration of the run",
+			},
+			"name": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: fmt.Sprintf("The name of the key. Defaults to %s for created keys", defaultEphemeralKeyName),
+			},
+			"ttl": schema.StringAttribute{
+				Optional: true,
+				Description: fmt.Sprintf("How long a created key stays valid (e.g., 30m, 2h), so that it stops working even if Terraform fails to delete it. "+
+					"Defaults to %s", defaultEphemeralKeyTTL),
+			},
+			"secret_access_key": schema.StringAttribute{
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The secret access key",
+			},
+			"expiration": schema.StringAttribute{
+				Computed:    true,
+				Description: "When the key expires, in RFC 3339 format. Empty if it never expires",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"bucket": schema.ListNestedBlock{
+				Description: "Permissions granted to a created key",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"bucket_id": schema.StringAttribute{
+							Required:    true,
+							Description: "The bucket ID",
+						},
+						"read": schema.BoolAttribute{
+							Optional:    true,
+							Description: "Grant read permission",
+						},
+						"write": schema.BoolAttribute{
+							Optional:    true,
+							Description: "Grant write permission",
+						},
+						"owner": schema.BoolAttribute{
+							Optional:    true,
+							Description: "Grant owner permission",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *garageKeyEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GarageClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *GarageClient, got %T", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+func (r *garageKeyEphemeralResource) ValidateConfig(ctx context.Context, req ephemeral.ValidateConfigRequest, resp *ephemeral.ValidateConfigResponse) {
+	var config garageKeyEphemeralModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.TTL.IsNull() && !config.TTL.IsUnknown() {
+		ttl, err := time.ParseDuration(config.TTL.ValueString())
+		if err != nil || ttl <= 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("ttl"), "Invalid ttl", fmt.Sprintf("ttl must be a positive duration such as 30m or 2h, got %q", config.TTL.ValueString()))
+		}
+	}
+
+	if config.AccessKeyID.IsNull() {
+		return
+	}
+
+	// Settings of an existing key are managed by its garage_key resource
+	if !config.TTL.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("ttl"), "Conflicting configuration", "ttl can only be set for created keys, not with access_key_id")
+	}
+
+	if !config.Name.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("name"), "Conflicting configuration", "name can only be set for created keys, not with access_key_id")
+	}
+
+	if len(config.Bucket) > 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("bucket"), "Conflicting configuration", "bucket can only be set for created keys, not with access_key_id")
+	}
+}
+
+func (r *garageKeyEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var data garageKeyEphemeralModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.client == nil {
+		if req.ClientCapabilities.DeferralAllowed {
+			resp.Deferred = &ephemeral.Deferred{Reason: ephemeral.DeferredReasonProviderConfigUnknown}
+			return
+		}
+
+		resp.Diagnostics.AddError("Provider not configured", "The provider configuration is not known yet, so the key cannot be opened")
+
+		return
+	}
+
+	var (
+		key *garage.GetKeyInfoResponse
+		err error
+	)
+
+	if data.AccessKeyID.IsNull() {
+		key, err = r.createKey(ctx, data)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to create key", err.Error())
+			return
+		}
+
+		createdID, _ := json.Marshal(key.AccessKeyId)
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, ephemeralKeyPrivateKey, createdID)...)
+	} else {
+		key, err = r.readKey(ctx, data.AccessKeyID.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to read key", err.Error())
+			return
+		}
+	}
+
+	data.AccessKeyID = types.StringValue(key.AccessKeyId)
+	data.Name = types.StringValue(key.Name)
+	data.SecretAccessKey = types.StringValue("")
+	data.Expiration = types.StringValue("")
+
+	if secret := key.SecretAccessKey.Get(); secret != nil {
+		data.SecretAccessKey = types.StringValue(*secret)
+	}
+
+	if expiration, ok := key.GetExpirationOk(); ok && expiration != nil {
+		data.Expiration = types.StringValue(expiration.UTC().Format(time.RFC3339))
+	}
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
+
+func (r *garageKeyEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	raw, diags := req.Private.GetKey(ctx, ephemeralKeyPrivateKey)
+	resp.Diagnostics.Append(diags...)
+
+	// Existing keys read by Open are left alone
+	if resp.Diagnostics.HasError() || raw == nil {
+		return
+	}
+
+	var keyID string
+	if err := json.Unmarshal(raw, &keyID); err != nil {
+		resp.Diagnostics.AddError("Failed to read private data", err.Error())
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("Provider not configured", fmt.Sprintf("Key %s cannot be deleted, it stays valid until it expires", keyID))
+		return
+	}
+
+	if err := deleteGarageKey(ctx, r.client, keyID); err != nil {
+		resp.Diagnostics.AddError("Failed to delete key", fmt.Sprintf("%s. It stays valid until it expires", err))
+	}
+}
+
+// createKey creates a key expiring after the ttl, with the configured bucket permissions
+func (r *garageKeyEphemeralResource) createKey(ctx context.Context, data garageKeyEphemeralModel) (*garage.GetKeyInfoResponse, error) {
+	// The key is deleted again by Close
+	endpoints := []string{"CreateKey", "DeleteKey"}
+	if len(data.Bucket) > 0 {
+		endpoints = append(endpoints, "AllowBucketKey")
+	}
+
+	if err := r.client.requireEndpoints("Opening a garage_key ephemeral resource", endpoints...); err != nil {
+		return nil, err
+	}
+
+	if err := r.client.requireGarageVersion("Opening a garage_key ephemeral resource, which creates an expiring key,", "2.0.0"); err != nil {
+		return nil, err
+	}
+
+	ttl := defaultEphemeralKeyTTL
+	if !data.TTL.IsNull() {
+		// Validated by ValidateConfig
+		ttl, _ = time.ParseDuration(data.TTL.ValueString())
+	}
+
+	name := defaultEphemeralKeyName
+	if !data.Name.IsNull() {
+		name = data.Name.ValueString()
+	}
+
+	keyBody := garage.NewUpdateKeyRequestBody()
+	keyBody.SetName(name)
+	keyBody.SetExpiration(time.Now().Add(ttl).UTC())
+
+	key, resp, err := r.client.Client.AccessKeyAPI.CreateKey(ctx).Body(*keyBody).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key: %w", err)
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	for _, bucket := range data.Bucket {
+		perms := garage.NewApiBucketKeyPerm()
+		perms.SetRead(bucket.Read.ValueBool())
+		perms.SetWrite(bucket.Write.ValueBool())
+		perms.SetOwner(bucket.Owner.ValueBool())
+
+		request := garage.NewBucketKeyPermChangeRequest(key.AccessKeyId, bucket.BucketID.ValueString(), *perms)
+
+		_, permResp, err := r.client.Client.PermissionAPI.AllowBucketKey(ctx).Body(*request).Execute()
+		if permResp != nil && permResp.Body != nil {
+			_ = permResp.Body.Close()
+		}
+
+		if err != nil {
+			// Don't leave a key nobody knows about behind
+			if deleteErr := deleteGarageKey(ctx, r.client, key.AccessKeyId); deleteErr != nil {
+				tflog.Warn(ctx, "Failed to delete ephemeral key", map[string]interface{}{"access_key_id": key.AccessKeyId, "error": deleteErr.Error()})
+			}
+
+			return nil, fmt.Errorf("failed to grant permissions on bucket %s: %w", bucket.BucketID.ValueString(), err)
+		}
+	}
+
+	return key, nil
+}
+
+func (r *garageKeyEphemeralResource) readKey(ctx context.Context, keyID string) (*garage.GetKeyInfoResponse, error) {
+	if err := r.client.requireEndpoints("Opening a garage_key ephemeral resource", "GetKeyInfo"); err != nil {
+		return nil, err
+	}
+
+	key, resp, err := r.client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(keyID).ShowSecretKey(true).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", keyID, err)
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	return key, nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	sdkdiag "github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	sdkschema "github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// frameworkProvider serves the resources ported from the SDKv2 provider and what it cannot serve, such as
+// ephemeral resources. It is muxed with Provider() in main.go, which requires both to have the same provider schema.
+type frameworkProvider struct {
+	clients *clientCache
+}
+
+var _ provider.ProviderWithEphemeralResources = &frameworkProvider{}
+
+type frameworkProviderModel struct {
+	Scheme             types.String               `tfsdk:"scheme"`
+	Host               types.String               `tfsdk:"host"`
+	Token              types.String               `tfsdk:"token"`
+	TokenFile          types.String               `tfsdk:"token_file"`
+	CACertFile         types.String               `tfsdk:"ca_cert_file"`
+	CACertPEM          types.String               `tfsdk:"ca_cert_pem"`
+	ClientCert         types.String               `tfsdk:"client_cert"`
+	ClientKey          types.String               `tfsdk:"client_key"`
+	InsecureSkipVerify types.Bool                 `tfsdk:"insecure_skip_verify"`
+	SkipHealthCheck    types.Bool                 `tfsdk:"skip_health_check"`
+	MaxRetries         types.Int64                `tfsdk:"max_retries"`
+	RetryMinWait       types.String               `tfsdk:"retry_min_wait"`
+	RetryMaxWait       types.String               `tfsdk:"retry_max_wait"`
+	S3Endpoint         []frameworkS3EndpointModel `tfsdk:"s3_endpoint"`
+}
+
+type frameworkS3EndpointModel struct {
+	URL             types.String `tfsdk:"url"`
+	ForcePathStyle  types.Bool   `tfsdk:"force_path_style"`
+	Region          types.String `tfsdk:"region"`
+	AccessKeyID     types.String `tfsdk:"access_key_id"`
+	SecretAccessKey types.String `tfsdk:"secret_access_key"`
+}
+
+// NewFrameworkProvider returns the framework provider with a client cache of its own. main.go uses
+// newFrameworkProvider instead, to share the cache with the SDKv2 provider.
+func NewFrameworkProvider() provider.Provider {
+	return newFrameworkProvider(newClientCache())
+}
+
+func newFrameworkProvider(clients *clientCache) provider.Provider {
+	return &frameworkProvider{clients: clients}
+}
+
+func (p *frameworkProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "garage"
+}
+
+// Schema mirrors the SDKv2 provider schema, which stays the reference for descriptions, defaults and validation
+func (p *frameworkProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	sdkSchema := Provider().Schema
+	s3Schema := sdkSchema["s3_endpoint"].Elem.(*sdkschema.Resource).Schema
+
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"scheme":               frameworkStringAttribute(sdkSchema["scheme"]),
+			"host":                 frameworkStringAttribute(sdkSchema["host"]),
+			"token":                frameworkStringAttribute(sdkSchema["token"]),
+			"token_file":           frameworkStringAttribute(sdkSchema["token_file"]),
+			"ca_cert_file":         frameworkStringAttribute(sdkSchema["ca_cert_file"]),
+			"ca_cert_pem":          frameworkStringAttribute(sdkSchema["ca_cert_pem"]),
+			"client_cert":          frameworkStringAttribute(sdkSchema["client_cert"]),
+			"client_key":           frameworkStringAttribute(sdkSchema["client_key"]),
+			"skip_health_check":    schema.BoolAttribute{Optional: true, Description: sdkSchema["skip_health_check"].Description},
+			"insecure_skip_verify": schema.BoolAttribute{Optional: true, Description: sdkSchema["insecure_skip_verify"].Description},
+			"max_retries":          schema.Int64Attribute{Optional: true, Description: sdkSchema["max_retries"].Description},
+			"retry_min_wait":       frameworkStringAttribute(sdkSchema["retry_min_wait"]),
+			"retry_max_wait":       frameworkStringAttribute(sdkSchema["retry_max_wait"]),
+		},
+		Blocks: map[string]schema.Block{
+			"s3_endpoint": schema.ListNestedBlock{
+				Description: sdkSchema["s3_endpoint"].Description,
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"url":               frameworkStringAttribute(s3Schema["url"]),
+						"force_path_style":  schema.BoolAttribute{Optional: true, Description: s3Schema["force_path_style"].Description},
+						"region":            frameworkStringAttribute(s3Schema["region"]),
+						"access_key_id":     frameworkStringAttribute(s3Schema["access_key_id"]),
+						"secret_access_key": frameworkStringAttribute(s3Schema["secret_access_key"]),
+					},
+				},
+			},
+		},
+	}
+}
+
+func frameworkStringAttribute(s *sdkschema.Schema) schema.StringAttribute {
+	return schema.StringAttribute{
+		Optional:    true,
+		Sensitive:   s.Sensitive,
+		Description: s.Description,
+	}
+}
+
+// Configure applies the same environment variables and defaults as the SDKv2 provider, so that both
+// share the client of their clientCache
+func (p *frameworkProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config frameworkProviderModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Configure is called again with the values once they are known, during apply
+	if !frameworkConfigKnown(config) {
+		return
+	}
+
+	settings := providerSettings{
+		Scheme:             stringWithDefault(config.Scheme, envWithDefault("GARAGE_ADMIN_SCHEME", "http")),
+		Host:               stringWithDefault(config.Host, os.Getenv("GARAGE_ADMIN_HOST")),
+		Token:              config.Token.ValueString(),
+		TokenFile:          config.TokenFile.ValueString(),
+		CACertFile:         config.CACertFile.ValueString(),
+		CACertPEM:          config.CACertPEM.ValueString(),
+		ClientCert:         config.ClientCert.ValueString(),
+		ClientKey:          config.ClientKey.ValueString(),
+		InsecureSkipVerify: config.InsecureSkipVerify.ValueBool(),
+		SkipHealthCheck:    config.SkipHealthCheck.ValueBool(),
+		MaxRetries:         defaultMaxRetries,
+		RetryMinWait:       stringWithDefault(config.RetryMinWait, defaultRetryMinWait.String()),
+		RetryMaxWait:       stringWithDefault(config.RetryMaxWait, defaultRetryMaxWait.String()),
+		S3:                 S3Config{ForcePathStyle: true},
+	}
+
+	if !config.MaxRetries.IsNull() {
+		settings.MaxRetries = int(config.MaxRetries.ValueInt64())
+	}
+
+	if len(config.S3Endpoint) > 0 {
+		s3Endpoint := config.S3Endpoint[0]
+		settings.S3 = S3Config{
+			Endpoint:        s3Endpoint.URL.ValueString(),
+			Region:          stringWithDefault(s3Endpoint.Region, defaultS3Region),
+			ForcePathStyle:  s3Endpoint.ForcePathStyle.IsNull() || s3Endpoint.ForcePathStyle.ValueBool(),
+			AccessKeyID:     s3Endpoint.AccessKeyID.ValueString(),
+			SecretAccessKey: s3Endpoint.SecretAccessKey.ValueString(),
+		}
+	}
+
+	client, diags := p.clients.configure(ctx, settings)
+	appendSDKDiagnostics(&resp.Diagnostics, diags)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.EphemeralResourceData = client
+	resp.ResourceData = client
+	resp.DataSourceData = client
+}
+
+func (p *frameworkProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		NewGarageKeyResource,
+		NewGarageBucketResource,
+		NewGarageBucketKeyResource,
+	}
+}
+
+func (p *frameworkProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return nil
+}
+
+func (p *frameworkProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		NewGarageKeyEphemeralResource,
+	}
+}
+
+// frameworkConfigKnown reports whether every provider setting is known
+func frameworkConfigKnown(config frameworkProviderModel) bool {
+	values := []interface{ IsUnknown() bool }{
+		config.Scheme, config.Host, config.Token, config.TokenFile, config.CACertFile, config.CACertPEM, config.ClientCert,
+		config.ClientKey, config.InsecureSkipVerify, config.SkipHealthCheck, config.MaxRetries, config.RetryMinWait, config.RetryMaxWait,
+	}
+
+	for _, s3Endpoint := range config.S3Endpoint {
+		values = append(values, s3Endpoint.URL, s3Endpoint.ForcePathStyle, s3Endpoint.Region, s3Endpoint.AccessKeyID, s3Endpoint.SecretAccessKey)
+	}
+
+	for _, v := range values {
+		if v.IsUnknown() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func stringWithDefault(v types.String, defaultValue string) string {
+	if v.IsNull() || v.IsUnknown() {
+		return defaultValue
+	}
+
+	return v.ValueString()
+}
+
+func envWithDefault(key, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return defaultValue
+}
+
+// appendSDKDiagnostics copies diagnostics of the code shared with the SDKv2 provider
+func appendSDKDiagnostics(diags *diag.Diagnostics, sdkDiags sdkdiag.Diagnostics) {
+	for _, d := range sdkDiags {
+		if d.Severity == sdkdiag.Error {
+			diags.AddError(d.Summary, d.Detail)
+		} else {
+			diags.AddWarning(d.Summary, d.Detail)
+		}
+	}
+}
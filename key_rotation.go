@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// keyRotationAction is what the next apply does about the rotation of a key
+type keyRotationAction int
+
+const (
+	keyRotationNone keyRotationAction = iota
+	keyRotationRotate
+	keyRotationRevokePrevious
+	// A rotation is requested while the previous key is in its grace period, rotating would delete it
+	keyRotationBlocked
+)
+
+// planKeyRotation decides what the next apply does. A rotation due to rotate_after waits for the grace
+// period of the previous key to end, one requested through rotation_triggers is refused until then.
+func planKeyRotation(rotationDue, triggersChanged, hasPrevious, previousRevocable bool) keyRotationAction {
+	switch {
+	case rotationDue && hasPrevious && !previousRevocable && triggersChanged:
+		return keyRotationBlocked
+	case rotationDue && hasPrevious && !previousRevocable:
+		return keyRotationNone
+	case rotationDue:
+		return keyRotationRotate
+	case hasPrevious && previousRevocable:
+		return keyRotationRevokePrevious
+	default:
+		return keyRotationNone
+	}
+}
+
+// modifyRotationPlan plans a rotation when rotation_triggers change or the key is older than
+// rotate_after, and the revocation of the previous key once its grace period is over
+func modifyRotationPlan(plan *garageKeyModel, state garageKeyModel, diags *diag.Diagnostics) {
+	now := time.Now()
+	// Null and empty triggers are the same, as SDKv2 did not tell them apart
+	triggersChanged := !plan.RotationTriggers.Equal(state.RotationTriggers) &&
+		(plan.RotationTriggers.IsUnknown() || len(plan.RotationTriggers.Elements())+len(state.RotationTriggers.Elements()) > 0)
+	previousID := state.PreviousAccessKeyID.ValueString()
+	gracePeriod := plan.RotationGracePeriod.ValueString()
+	rotatedAt := state.RotatedAt.ValueString()
+
+	action := planKeyRotation(
+		keyRotationDue(triggersChanged, plan.RotateAfter.ValueString(), state.Created.ValueString(), now),
+		triggersChanged,
+		previousID != "",
+		previousKeyRevocable(gracePeriod, rotatedAt, now),
+	)
+
+	switch action {
+	case keyRotationBlocked:
+		// Both parse, or the previous key would be revocable
+		rotated, _ := time.Parse(time.RFC3339, rotatedAt)
+		grace, _ := time.ParseDuration(gracePeriod)
+
+		diags.AddAttributeError(path.Root("rotation_triggers"), "Rotation during the grace period",
+			fmt.Sprintf("key %s cannot be rotated until %s, when the grace period of the previous key %s ends, as rotating "+
+				"deletes the previous key: revert the change of rotation_triggers and apply it again then",
+				state.ID.ValueString(), rotated.Add(grace).UTC().Format(time.RFC3339), previousID))
+	case keyRotationRotate:
+		plan.ID = types.StringUnknown()
+		plan.AccessKeyID = types.StringUnknown()
+		plan.SecretAccessKey = types.StringUnknown()
+		plan.EncryptedSecretAccessKey = types.StringUnknown()
+		plan.Created = types.StringUnknown()
+		plan.Expired = types.BoolUnknown()
+		plan.PreviousAccessKeyID = types.StringUnknown()
+		plan.PreviousSecretAccessKey = types.StringUnknown()
+		plan.EncryptedPreviousSecretAccessKey = types.StringUnknown()
+		plan.RotatedAt = types.StringUnknown()
+	case keyRotationRevokePrevious:
+		plan.PreviousAccessKeyID = types.StringValue("")
+		plan.PreviousSecretAccessKey = types.StringValue("")
+		plan.EncryptedPreviousSecretAccessKey = types.StringValue("")
+	}
+}
+
+// keyRotationDue reports whether rotation_triggers changed or the key is older than rotate_after
+func keyRotationDue(triggersChanged bool, rotateAfter, created string, now time.Time) bool {
+	if triggersChanged {
+		return true
+	}
+
+	after, err := time.ParseDuration(rotateAfter)
+	if err != nil || after == 0 {
+		return false
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return false
+	}
+
+	return now.After(createdAt.Add(after))
+}
+
+// previousKeyRevocable reports whether the grace period of the previous key is over. Without
+// a grace period the previous key is revoked on the apply following the rotation.
+func previousKeyRevocable(gracePeriod, rotatedAt string, now time.Time) bool {
+	grace, err := time.ParseDuration(gracePeriod)
+	if err != nil || grace == 0 {
+		return true
+	}
+
+	rotated, err := time.Parse(time.RFC3339, rotatedAt)
+	if err != nil {
+		return true
+	}
+
+	return now.After(rotated.Add(grace))
+}
+
+// rotateGarageKey replaces the key with a successor having the same settings and bucket permissions,
+// and keeps the current key as the previous one. A key left over from an earlier rotation is revoked,
+// which modifyRotationPlan only plans once its grace period is over.
+func rotateGarageKey(ctx context.Context, client *GarageClient, data *garageKeyModel, state garageKeyModel) error {
+	if err := client.requireEndpoints("Rotating a key", "GetKeyInfo", "CreateKey", "AllowBucketKey", "UpdateKey", "DeleteKey"); err != nil {
+		return err
+	}
+
+	currentID := state.ID.ValueString()
+
+	if previousID := state.PreviousAccessKeyID.ValueString(); previousID != "" {
+		if err := deleteGarageKey(ctx, client, previousID); err != nil {
+			return err
+		}
+	}
+
+	current, resp, err := client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(currentID).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to read key %s: %w", currentID, err)
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	keyBody, _ := newKeyCreateBody(*data)
+
+	successor, resp, err := client.Client.AccessKeyAPI.CreateKey(ctx).Body(*keyBody).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to create successor key: %w", err)
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	if err := copyBucketPermissions(ctx, client, current.Buckets, successor.AccessKeyId); err != nil {
+		// Don't leave a key nobody knows about behind
+		if deleteErr := deleteGarageKey(ctx, client, successor.AccessKeyId); deleteErr != nil {
+			tflog.Warn(ctx, "Failed to delete successor key", map[string]interface{}{"access_key_id": successor.AccessKeyId, "error": deleteErr.Error()})
+		}
+
+		return err
+	}
+
+	rotatedAt := time.Now().UTC()
+
+	if err := expireRotatedKey(ctx, client, current, data.RotationGracePeriod.ValueString(), rotatedAt); err != nil {
+		return err
+	}
+
+	secret := ""
+	if s := successor.SecretAccessKey.Get(); s != nil {
+		secret = *s
+	}
+
+	data.ID = types.StringValue(successor.AccessKeyId)
+	data.AccessKeyID = types.StringValue(successor.AccessKeyId)
+	data.PreviousAccessKeyID = types.StringValue(currentID)
+	// The current secret is kept in the form it is stored in, plaintext or encrypted
+	data.PreviousSecretAccessKey = state.SecretAccessKey
+	data.EncryptedPreviousSecretAccessKey = state.EncryptedSecretAccessKey
+	data.RotatedAt = types.StringValue(rotatedAt.Format(time.RFC3339))
+
+	return setKeySecret(ctx, data, &data.SecretAccessKey, &data.EncryptedSecretAccessKey, secret)
+}
+
+// copyBucketPermissions grants a key the permissions another key has on its buckets
+func copyBucketPermissions(ctx context.Context, client *GarageClient, buckets []garage.KeyInfoBucketResponse, keyID string) error {
+	for _, bucket := range buckets {
+		perms := bucket.Permissions
+		if !perms.GetRead() && !perms.GetWrite() && !perms.GetOwner() {
+			continue
+		}
+
+		request := garage.NewBucketKeyPermChangeRequest(keyID, bucket.Id, perms)
+
+		_, resp, err := client.Client.PermissionAPI.AllowBucketKey(ctx).Body(*request).Execute()
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to copy permissions on bucket %s to key %s: %w", bucket.Id, keyID, err)
+		}
+	}
+
+	return nil
+}
+
+// expireRotatedKey makes a rotated key expire at the end of its grace period, so that it stops working
+// even if Terraform does not run again. An earlier expiration is kept.
+func expireRotatedKey(ctx context.Context, client *GarageClient, key *garage.GetKeyInfoResponse, gracePeriod string, rotatedAt time.Time) error {
+	grace, err := time.ParseDuration(gracePeriod)
+	if err != nil || grace == 0 {
+		return nil
+	}
+
+	expiration := rotatedAt.Add(grace)
+	if current, ok := key.GetExpirationOk(); ok && current != nil && current.Before(expiration) {
+		return nil
+	}
+
+	keyBody := garage.NewUpdateKeyRequestBody()
+	keyBody.SetExpiration(expiration)
+
+	_, resp, err := client.Client.AccessKeyAPI.UpdateKey(ctx).Id(key.AccessKeyId).UpdateKeyRequestBody(*keyBody).Execute()
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to set the expiration of rotated key %s: %w", key.AccessKeyId, err)
+	}
+
+	return nil
+}
+
+// deleteGarageKey deletes a key, succeeding if it is already gone
+func deleteGarageKey(ctx context.Context, client *GarageClient, keyID string) error {
+	resp, err := client.Client.AccessKeyAPI.DeleteKey(ctx).Id(keyID).Execute()
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	if err != nil {
+		// The key is already gone, nothing left to revoke
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+
+		return fmt.Errorf("failed to delete key %s, it is still valid on the cluster: %w", keyID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestPlanKeyRotation(t *testing.T) {
+	tests := []struct {
+		name              string
+		rotationDue       bool
+		triggersChanged   bool
+		hasPrevious       bool
+		previousRevocable bool
+		want              keyRotationAction
+	}{
+		{name: "nothing to do", want: keyRotationNone},
+		{name: "first rotation", rotationDue: true, want: keyRotationRotate},
+		{name: "rotation after the grace period", rotationDue: true, hasPrevious: true, previousRevocable: true, want: keyRotationRotate},
+		{name: "rotate_after during the grace period", rotationDue: true, hasPrevious: true, want: keyRotationNone},
+		{name: "triggers during the grace period", rotationDue: true, triggersChanged: true, hasPrevious: true, want: keyRotationBlocked},
+		{name: "grace period over", hasPrevious: true, previousRevocable: true, want: keyRotationRevokePrevious},
+		{name: "grace period running", hasPrevious: true, want: keyRotationNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planKeyRotation(tt.rotationDue, tt.triggersChanged, tt.hasPrevious, tt.previousRevocable); got != tt.want {
+				t.Errorf("planKeyRotation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyRotationDue(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		triggersChanged bool
+		rotateAfter     string
+		created         string
+		want            bool
+	}{
+		{name: "triggers changed", triggersChanged: true, want: true},
+		{name: "no rotate_after", created: "2020-01-01T00:00:00Z", want: false},
+		{name: "old enough", rotateAfter: "720h", created: "2026-08-01T00:00:00Z", want: true},
+		{name: "too recent", rotateAfter: "720h", created: "2026-09-15T00:00:00Z", want: false},
+		{name: "unknown creation date", rotateAfter: "720h", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyRotationDue(tt.triggersChanged, tt.rotateAfter, tt.created, now); got != tt.want {
+				t.Errorf("keyRotationDue() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviousKeyRevocable(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		gracePeriod string
+		rotatedAt   string
+		want        bool
+	}{
+		{name: "no grace period", rotatedAt: "2026-10-01T11:00:00Z", want: true},
+		{name: "grace period running", gracePeriod: "168h", rotatedAt: "2026-09-28T00:00:00Z", want: false},
+		{name: "grace period over", gracePeriod: "168h", rotatedAt: "2026-09-20T00:00:00Z", want: true},
+		{name: "unknown rotation date", gracePeriod: "168h", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previousKeyRevocable(tt.gracePeriod, tt.rotatedAt, now); got != tt.want {
+				t.Errorf("previousKeyRevocable() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGarageKeyPlanRotationDuringGracePeriod(t *testing.T) {
+	rotatedAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	triggers := func(value string) tftypes.Value {
+		return tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+			"rotation": tftypes.NewValue(tftypes.String, value),
+		})
+	}
+
+	prior := map[string]tftypes.Value{
+		"id":                     tftypes.NewValue(tftypes.String, "GK2"),
+		"name":                   tftypes.NewValue(tftypes.String, "app"),
+		"access_key_id":          tftypes.NewValue(tftypes.String, "GK2"),
+		"secret_access_key":      tftypes.NewValue(tftypes.String, "secret"),
+		"previous_access_key_id": tftypes.NewValue(tftypes.String, "GK1"),
+		"rotated_at":             tftypes.NewValue(tftypes.String, rotatedAt),
+		"rotation_grace_period":  tftypes.NewValue(tftypes.String, "168h"),
+		"rotation_triggers":      triggers("1"),
+		"fetch_secret_on_read":   tftypes.NewValue(tftypes.Bool, false),
+		"allow_create_bucket":    tftypes.NewValue(tftypes.Bool, false),
+		"never_expires":          tftypes.NewValue(tftypes.Bool, false),
+	}
+
+	config := map[string]tftypes.Value{
+		"name":                  tftypes.NewValue(tftypes.String, "app"),
+		"rotation_grace_period": tftypes.NewValue(tftypes.String, "168h"),
+		"rotation_triggers":     triggers("2"),
+	}
+
+	resp, _ := planGarageKeyResponse(t, prior, config)
+
+	if len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Severity != tfprotov6.DiagnosticSeverityError ||
+		!strings.Contains(resp.Diagnostics[0].Detail, "previous key GK1") {
+		t.Fatalf("PlanResourceChange() diagnostics = %+v, want an error about the grace period of GK1", resp.Diagnostics)
+	}
+}
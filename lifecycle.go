@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+// lifecycleRuleResource is the schema of a lifecycle rule, shared by garage_bucket and garage_bucket_lifecycle_configuration
+func lifecycleRuleResource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"id": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringLenBetween(1, 255),
+				Description:  "Unique identifier of the rule",
+			},
+			"enabled": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether the rule is applied",
+			},
+			"prefix": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only apply the rule to objects with a key starting with this prefix",
+			},
+			"object_size_greater_than": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Only apply the rule to objects larger than this many bytes",
+			},
+			"object_size_less_than": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Only apply the rule to objects smaller than this many bytes",
+			},
+			"expiration_days": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Delete objects this many days after they were created",
+			},
+			"expiration_date": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsRFC3339Time,
+				Description:  "Delete objects from this date on, as an RFC3339 timestamp at midnight UTC such as 2030-01-01T00:00:00Z",
+			},
+			"abort_incomplete_multipart_upload_days": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Abort multipart uploads this many days after they were started",
+			},
+		},
+	}
+}
+
+// LifecycleConfiguration represents an S3 bucket lifecycle configuration.
+type LifecycleConfiguration struct {
+	XMLName xml.Name `xml:"LifecycleConfiguration"`
+	Rules   []Rule   `xml:"Rule"`
+}
+
+type Rule struct {
+	ID                             string                          `xml:"ID"`
+	Status                         string                          `xml:"Status"`
+	Filter                         *Filter                         `xml:"Filter,omitempty"`
+	Expiration                     *Expiration                     `xml:"Expiration,omitempty"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload,omitempty"`
+}
+
+// Filter selects the objects a rule applies to. Several conditions must be wrapped in And.
+type Filter struct {
+	And                   *FilterAnd `xml:"And,omitempty"`
+	Prefix                *string    `xml:"Prefix,omitempty"`
+	ObjectSizeGreaterThan *int64     `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64     `xml:"ObjectSizeLessThan,omitempty"`
+}
+
+type FilterAnd struct {
+	Prefix                *string `xml:"Prefix,omitempty"`
+	ObjectSizeGreaterThan *int64  `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64  `xml:"ObjectSizeLessThan,omitempty"`
+}
+
+type Expiration struct {
+	Days int    `xml:"Days,omitempty"`
+	Date string `xml:"Date,omitempty"`
+}
+
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+// expirationDaysRuleID is the ID of the lifecycle rule created by the expiration_days attribute of garage_bucket
+const expirationDaysRuleID = "expire-after-days"
+
+// expirationDaysRule returns the lifecycle rule created by the expiration_days attribute of garage_bucket
+func expirationDaysRule(days int) Rule {
+	return Rule{
+		ID:     expirationDaysRuleID,
+		Status: "Enabled",
+		Expiration: &Expiration{
+			Days: days,
+		},
+	}
+}
+
+// findLifecycleRule returns the rule with the given ID, nil if there is none
+func findLifecycleRule(rules []Rule, id string) *Rule {
+	for i := range rules {
+		if rules[i].ID == id {
+			return &rules[i]
+		}
+	}
+
+	return nil
+}
+
+// mergeLifecycleRule returns rules with rule added, replacing the rule with the same ID if there is one
+func mergeLifecycleRule(rules []Rule, rule Rule) []Rule {
+	merged := slices.Clone(rules)
+
+	if existing := findLifecycleRule(merged, rule.ID); existing != nil {
+		*existing = rule
+		return merged
+	}
+
+	return append(merged, rule)
+}
+
+// expandLifecycleRules converts lifecycle_rule blocks into S3 rules, rejecting rules Garage would refuse
+func expandLifecycleRules(raw []interface{}) ([]Rule, error) {
+	rules := make([]Rule, 0, len(raw))
+	ids := make(map[string]bool, len(raw))
+
+	for _, v := range raw {
+		if v == nil {
+			continue
+		}
+
+		block := v.(map[string]interface{})
+		id := block["id"].(string)
+
+		if ids[id] {
+			return nil, fmt.Errorf("lifecycle_rule %q is defined more than once", id)
+		}
+
+		ids[id] = true
+
+		rule := Rule{ID: id, Status: "Disabled"}
+		if block["enabled"].(bool) {
+			rule.Status = "Enabled"
+		}
+
+		var conditions FilterAnd
+
+		count := 0
+
+		if prefix := block["prefix"].(string); prefix != "" {
+			conditions.Prefix = &prefix
+			count++
+		}
+
+		greaterThan := int64(block["object_size_greater_than"].(int))
+		if greaterThan > 0 {
+			conditions.ObjectSizeGreaterThan = &greaterThan
+			count++
+		}
+
+		lessThan := int64(block["object_size_less_than"].(int))
+		if lessThan > 0 {
+			conditions.ObjectSizeLessThan = &lessThan
+			count++
+		}
+
+		if greaterThan > 0 && lessThan > 0 && lessThan <= greaterThan {
+			return nil, fmt.Errorf("lifecycle_rule %q: object_size_less_than must be greater than object_size_greater_than", id)
+		}
+
+		switch count {
+		case 0:
+		case 1:
+			rule.Filter = &Filter{
+				Prefix:                conditions.Prefix,
+				ObjectSizeGreaterThan: conditions.ObjectSizeGreaterThan,
+				ObjectSizeLessThan:    conditions.ObjectSizeLessThan,
+			}
+		default:
+			rule.Filter = &Filter{And: &conditions}
+		}
+
+		expirationDays := block["expiration_days"].(int)
+		expirationDate := block["expiration_date"].(string)
+
+		switch {
+		case expirationDays > 0 && expirationDate != "":
+			return nil, fmt.Errorf("lifecycle_rule %q: only one of expiration_days and expiration_date can be set", id)
+		case expirationDays > 0:
+			rule.Expiration = &Expiration{Days: expirationDays}
+		case expirationDate != "":
+			date, err := time.Parse(time.RFC3339, expirationDate)
+			if err != nil {
+				return nil, fmt.Errorf("lifecycle_rule %q: invalid expiration_date: %w", id, err)
+			}
+
+			// Garage only accepts dates at midnight UTC
+			if date.UTC() != date.UTC().Truncate(24*time.Hour) {
+				return nil, fmt.Errorf("lifecycle_rule %q: expiration_date must be at midnight UTC, such as %s", id, date.UTC().Truncate(24*time.Hour).Format(time.RFC3339))
+			}
+
+			rule.Expiration = &Expiration{Date: date.UTC().Format(time.RFC3339)}
+		}
+
+		if days := block["abort_incomplete_multipart_upload_days"].(int); days > 0 {
+			rule.AbortIncompleteMultipartUpload = &AbortIncompleteMultipartUpload{DaysAfterInitiation: days}
+		}
+
+		if rule.Expiration == nil && rule.AbortIncompleteMultipartUpload == nil {
+			return nil, fmt.Errorf("lifecycle_rule %q: set expiration_days, expiration_date or abort_incomplete_multipart_upload_days", id)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func flattenLifecycleRules(rules []Rule) []interface{} {
+	result := make([]interface{}, 0, len(rules))
+
+	for _, rule := range rules {
+		block := map[string]interface{}{
+			"id":                                     rule.ID,
+			"enabled":                                rule.Status == "Enabled",
+			"prefix":                                 "",
+			"object_size_greater_than":               0,
+			"object_size_less_than":                  0,
+			"expiration_days":                        0,
+			"expiration_date":                        "",
+			"abort_incomplete_multipart_upload_days": 0,
+		}
+
+		if rule.Filter != nil {
+			conditions := FilterAnd{
+				Prefix:                rule.Filter.Prefix,
+				ObjectSizeGreaterThan: rule.Filter.ObjectSizeGreaterThan,
+				ObjectSizeLessThan:    rule.Filter.ObjectSizeLessThan,
+			}
+			if rule.Filter.And != nil {
+				conditions = *rule.Filter.And
+			}
+
+			if conditions.Prefix != nil {
+				block["prefix"] = *conditions.Prefix
+			}
+
+			if conditions.ObjectSizeGreaterThan != nil {
+				block["object_size_greater_than"] = int(*conditions.ObjectSizeGreaterThan)
+			}
+
+			if conditions.ObjectSizeLessThan != nil {
+				block["object_size_less_than"] = int(*conditions.ObjectSizeLessThan)
+			}
+		}
+
+		if rule.Expiration != nil {
+			block["expiration_days"] = rule.Expiration.Days
+
+			if rule.Expiration.Date != "" {
+				// Normalise the date so that equivalent timestamps don't show a diff
+				if date, err := time.Parse(time.RFC3339, rule.Expiration.Date); err == nil {
+					block["expiration_date"] = date.UTC().Format(time.RFC3339)
+				} else {
+					block["expiration_date"] = rule.Expiration.Date
+				}
+			}
+		}
+
+		if rule.AbortIncompleteMultipartUpload != nil {
+			block["abort_incomplete_multipart_upload_days"] = rule.AbortIncompleteMultipartUpload.DaysAfterInitiation
+		}
+
+		result = append(result, block)
+	}
+
+	return result
+}
+
+// requireLifecycleConfiguration checks that the cluster serves lifecycle configurations on its S3 API
+func requireLifecycleConfiguration(client *GarageClient) error {
+	return client.requireGarageVersion("Lifecycle configuration", "0.9.0")
+}
+
+// setBucketLifecycleConfiguration replaces the lifecycle rules of a bucket using the S3-compatible API
+func setBucketLifecycleConfiguration(ctx context.Context, client *GarageClient, bucketID string, rules []Rule) error {
+	if err := requireLifecycleConfiguration(client); err != nil {
+		return err
+	}
+
+	xmlData, err := xml.MarshalIndent(LifecycleConfiguration{Rules: rules}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lifecycle config: %w", err)
+	}
+
+	return withBucketS3Session(ctx, client, bucketID, func(s *s3Session) error {
+		headers := map[string]string{"Content-Type": "application/xml"}
+
+		resp, err := s.do(ctx, http.MethodPut, "", url.Values{"lifecycle": {""}}, xmlData, headers)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if resp.Body != nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+			return s3ResponseError(resp)
+		}
+
+		return nil
+	})
+}
+
+// getBucketLifecycleConfiguration retrieves the lifecycle rules of a bucket, none if it has no lifecycle configuration
+func getBucketLifecycleConfiguration(ctx context.Context, client *GarageClient, bucketID string) ([]Rule, error) {
+	if err := requireLifecycleConfiguration(client); err != nil {
+		return nil, err
+	}
+
+	var lifecycleConfig LifecycleConfiguration
+
+	err := withBucketS3Session(ctx, client, bucketID, func(s *s3Session) error {
+		resp, err := s.do(ctx, http.MethodGet, "", url.Values{"lifecycle": {""}}, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if resp.Body != nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		if resp.StatusCode == http.StatusNotFound {
+			return nil // No lifecycle policy set
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return s3ResponseError(resp)
+		}
+
+		// Parse XML response
+		if err := xml.NewDecoder(resp.Body).Decode(&lifecycleConfig); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return lifecycleConfig.Rules, nil
+}
+
+// deleteBucketLifecycleConfiguration removes the lifecycle configuration from a bucket
+func deleteBucketLifecycleConfiguration(ctx context.Context, client *GarageClient, bucketID string) error {
+	if err := requireLifecycleConfiguration(client); err != nil {
+		return err
+	}
+
+	return withBucketS3Session(ctx, client, bucketID, func(s *s3Session) error {
+		resp, err := s.do(ctx, http.MethodDelete, "", url.Values{"lifecycle": {""}}, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if resp.Body != nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+			return s3ResponseError(resp)
+		}
+
+		return nil
+	})
+}
+
+// putBucketLifecycleRule adds a single rule to the lifecycle configuration of a bucket, or replaces the
+// rule with the same ID, keeping the other rules
+func putBucketLifecycleRule(ctx context.Context, client *GarageClient, bucketID string, rule Rule) error {
+	rules, err := getBucketLifecycleConfiguration(ctx, client, bucketID)
+	if err != nil {
+		return err
+	}
+
+	return setBucketLifecycleConfiguration(ctx, client, bucketID, mergeLifecycleRule(rules, rule))
+}
+
+// removeBucketLifecycleRule removes a single rule from the lifecycle configuration of a bucket,
+// deleting the configuration if no rule is left
+func removeBucketLifecycleRule(ctx context.Context, client *GarageClient, bucketID, ruleID string) error {
+	rules, err := getBucketLifecycleConfiguration(ctx, client, bucketID)
+	if err != nil {
+		return err
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(rules), func(rule Rule) bool {
+		return rule.ID == ruleID
+	})
+
+	// The rule is already gone
+	if len(remaining) == len(rules) {
+		return nil
+	}
+
+	if len(remaining) == 0 {
+		return deleteBucketLifecycleConfiguration(ctx, client, bucketID)
+	}
+
+	return setBucketLifecycleConfiguration(ctx, client, bucketID, remaining)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestLifecycleRulesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []interface{}
+		want  []Rule
+	}{
+		{
+			name: "expiration days without filter",
+			rules: []interface{}{
+				map[string]interface{}{"id": "expire", "expiration_days": 30},
+			},
+			want: []Rule{
+				{ID: "expire", Status: "Enabled", Expiration: &Expiration{Days: 30}},
+			},
+		},
+		{
+			name: "disabled rule with a prefix",
+			rules: []interface{}{
+				map[string]interface{}{"id": "debug", "enabled": false, "prefix": "debug/", "expiration_days": 7},
+			},
+			want: []Rule{
+				{ID: "debug", Status: "Disabled", Filter: &Filter{Prefix: ptr("debug/")}, Expiration: &Expiration{Days: 7}},
+			},
+		},
+		{
+			name: "several conditions",
+			rules: []interface{}{
+				map[string]interface{}{
+					"id":                       "dumps",
+					"prefix":                   "dumps/",
+					"object_size_greater_than": 1024,
+					"object_size_less_than":    4096,
+					"expiration_date":          "2030-01-01T00:00:00Z",
+				},
+			},
+			want: []Rule{
+				{
+					ID:     "dumps",
+					Status: "Enabled",
+					Filter: &Filter{And: &FilterAnd{
+						Prefix:                ptr("dumps/"),
+						ObjectSizeGreaterThan: ptr(int64(1024)),
+						ObjectSizeLessThan:    ptr(int64(4096)),
+					}},
+					Expiration: &Expiration{Date: "2030-01-01T00:00:00Z"},
+				},
+			},
+		},
+		{
+			name: "multipart uploads",
+			rules: []interface{}{
+				map[string]interface{}{"id": "uploads", "abort_incomplete_multipart_upload_days": 1},
+				map[string]interface{}{"id": "large", "object_size_greater_than": 100, "expiration_days": 1},
+			},
+			want: []Rule{
+				{ID: "uploads", Status: "Enabled", AbortIncompleteMultipartUpload: &AbortIncompleteMultipartUpload{DaysAfterInitiation: 1}},
+				{ID: "large", Status: "Enabled", Filter: &Filter{ObjectSizeGreaterThan: ptr(int64(100))}, Expiration: &Expiration{Days: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, resourceGarageBucketLifecycleConfiguration().Schema, map[string]interface{}{
+				"bucket_id": "bucket",
+				"rule":      tt.rules,
+			})
+
+			rules, err := expandLifecycleRules(d.Get("rule").([]interface{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Fatalf("expandLifecycleRules() = %#v, want %#v", rules, tt.want)
+			}
+
+			if err := d.Set("rule", flattenLifecycleRules(rules)); err != nil {
+				t.Fatal(err)
+			}
+
+			roundTrip, err := expandLifecycleRules(d.Get("rule").([]interface{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(roundTrip, tt.want) {
+				t.Errorf("expandLifecycleRules(flattenLifecycleRules()) = %#v, want %#v", roundTrip, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandLifecycleRulesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []interface{}
+		wantErr string
+	}{
+		{
+			name: "duplicate ID",
+			rules: []interface{}{
+				map[string]interface{}{"id": "expire", "expiration_days": 1},
+				map[string]interface{}{"id": "expire", "expiration_days": 2},
+			},
+			wantErr: "defined more than once",
+		},
+		{
+			name: "no action",
+			rules: []interface{}{
+				map[string]interface{}{"id": "empty", "prefix": "logs/"},
+			},
+			wantErr: "set expiration_days, expiration_date or abort_incomplete_multipart_upload_days",
+		},
+		{
+			name: "days and date",
+			rules: []interface{}{
+				map[string]interface{}{"id": "both", "expiration_days": 1, "expiration_date": "2030-01-01T00:00:00Z"},
+			},
+			wantErr: "only one of expiration_days and expiration_date",
+		},
+		{
+			name: "date not at midnight",
+			rules: []interface{}{
+				map[string]interface{}{"id": "noon", "expiration_date": "2030-01-01T12:00:00Z"},
+			},
+			wantErr: "must be at midnight UTC",
+		},
+		{
+			name: "empty size range",
+			rules: []interface{}{
+				map[string]interface{}{"id": "sizes", "object_size_greater_than": 100, "object_size_less_than": 100, "expiration_days": 1},
+			},
+			wantErr: "object_size_less_than must be greater than object_size_greater_than",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, resourceGarageBucketLifecycleConfiguration().Schema, map[string]interface{}{
+				"bucket_id": "bucket",
+				"rule":      tt.rules,
+			})
+
+			_, err := expandLifecycleRules(d.Get("rule").([]interface{}))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expandLifecycleRules() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeLifecycleRule(t *testing.T) {
+	retention := Rule{ID: "retention", Status: "Enabled", Expiration: &Expiration{Days: 90}}
+
+	tests := []struct {
+		name  string
+		rules []Rule
+		days  int
+		want  []Rule
+	}{
+		{
+			name: "no rules",
+			days: 7,
+			want: []Rule{expirationDaysRule(7)},
+		},
+		{
+			name:  "other rules are kept",
+			rules: []Rule{retention},
+			days:  7,
+			want:  []Rule{retention, expirationDaysRule(7)},
+		},
+		{
+			name:  "existing rule is replaced in place",
+			rules: []Rule{expirationDaysRule(30), retention},
+			days:  7,
+			want:  []Rule{expirationDaysRule(7), retention},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := slices.Clone(tt.rules)
+
+			if got := mergeLifecycleRule(rules, expirationDaysRule(tt.days)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeLifecycleRule() = %#v, want %#v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("mergeLifecycleRule() modified its argument: %#v", rules)
+			}
+		})
+	}
+}
+
+func TestFindLifecycleRule(t *testing.T) {
+	rules := []Rule{
+		{ID: "retention", Status: "Enabled", Expiration: &Expiration{Days: 90}},
+		expirationDaysRule(7),
+	}
+
+	if rule := findLifecycleRule(rules, expirationDaysRuleID); rule == nil || rule.Expiration.Days != 7 {
+		t.Errorf("findLifecycleRule(%q) = %#v, want the rule expiring after 7 days", expirationDaysRuleID, rule)
+	}
+
+	if rule := findLifecycleRule(rules, "missing"); rule != nil {
+		t.Errorf("findLifecycleRule(%q) = %#v, want nil", "missing", rule)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"log"
 
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6/tf6server"
+	"github.com/hashicorp/terraform-plugin-mux/tf5to6server"
+	"github.com/hashicorp/terraform-plugin-mux/tf6muxserver"
 )
 
 func main() {
@@ -13,12 +18,37 @@ func main() {
 	flag.BoolVar(&debugMode, "debug", false, "set to true to run the provider with support for debuggers like delve")
 	flag.Parse()
 
-	opts := &plugin.ServeOpts{
-		ProviderFunc: func() *schema.Provider {
-			return Provider()
+	ctx := context.Background()
+
+	// Both providers are configured with the same settings and share the client created for them
+	clients := newClientCache()
+
+	// Resources not yet ported to the framework provider are served by the SDKv2 provider, upgraded to protocol 6
+	upgradedSDKProvider, err := tf5to6server.UpgradeServer(ctx, newProvider(clients).GRPCProvider)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	providers := []func() tfprotov6.ProviderServer{
+		func() tfprotov6.ProviderServer {
+			return upgradedSDKProvider
 		},
-		Debug: debugMode,
+		providerserver.NewProtocol6(newFrameworkProvider(clients)),
+	}
+
+	muxServer, err := tf6muxserver.NewMuxServer(ctx, providers...)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	plugin.Serve(opts)
+	var serveOpts []tf6server.ServeOpt
+
+	if debugMode {
+		serveOpts = append(serveOpts, tf6server.WithManagedDebug())
+	}
+
+	err = tf6server.Serve("registry.terraform.io/d0ugal/garage", muxServer.ProviderServer, serveOpts...)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
@@ -2,51 +2,414 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 )
 
+// Provider returns the SDKv2 provider with a client cache of its own. main.go uses newProvider instead,
+// to share the cache with the framework provider.
 func Provider() *schema.Provider {
+	return newProvider(newClientCache())
+}
+
+func newProvider(clients *clientCache) *schema.Provider {
 	return &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"scheme": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				Default:     "http",
-				Description: "The scheme to use for the Garage admin API",
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_ADMIN_SCHEME", "http"),
+				Description: "The scheme to use for the Garage admin API. Can also be set with the GARAGE_ADMIN_SCHEME environment variable",
 			},
 			"host": {
 				Type:        schema.TypeString,
-				Required:    true,
-				Description: "The host and port for the Garage admin API (e.g., 127.0.0.1:3903)",
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("GARAGE_ADMIN_HOST", nil),
+				Description: "The host and port for the Garage admin API (e.g., 127.0.0.1:3903). Required, unless set with the GARAGE_ADMIN_HOST environment variable",
 			},
 			"token": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Sensitive:   true,
-				Description: "The admin token for the Garage admin API",
+				Type:          schema.TypeString,
+				Optional:      true,
+				Sensitive:     true,
+				ConflictsWith: []string{"token_file"},
+				Description:   "The admin token for the Garage admin API. Defaults to the GARAGE_ADMIN_TOKEN environment variable when neither token nor token_file is set",
+			},
+			"token_file": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"token"},
+				Description:   "Path to a file containing the admin token for the Garage admin API, like Garage's admin_token_file. Surrounding whitespace is ignored",
+			},
+			"ca_cert_file": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"ca_cert_pem"},
+				Description:   "Path to a PEM-encoded CA bundle to trust, in addition to the system roots, for the admin and S3 APIs",
+			},
+			"ca_cert_pem": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"ca_cert_file"},
+				Description:   "PEM-encoded CA bundle to trust, in addition to the system roots, for the admin and S3 APIs",
+			},
+			"client_cert": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				RequiredWith: []string{"client_key"},
+				Description:  "PEM-encoded client certificate presented to servers requiring mutual TLS",
+			},
+			"client_key": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Sensitive:    true,
+				RequiredWith: []string{"client_cert"},
+				Description:  "PEM-encoded private key of the client certificate",
+			},
+			"skip_health_check": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Skip checking that the admin API is reachable and detecting its capabilities when the provider is configured",
+			},
+			"insecure_skip_verify": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Skip verification of the server certificate. Only use this for testing",
+			},
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      defaultMaxRetries,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of times a request failing with a transient error (connection refused, 429, 500, 502, 503, 504) is retried. Only idempotent requests are retried after reaching the server. Set to 0 to disable retries",
+			},
+			"retry_min_wait": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      defaultRetryMinWait.String(),
+				ValidateFunc: validateDuration,
+				Description:  "Time to wait before the first retry, doubled on each further attempt (e.g., 500ms, 1s)",
+			},
+			"retry_max_wait": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      defaultRetryMaxWait.String(),
+				ValidateFunc: validateDuration,
+				Description:  "Maximum time to wait between retries, including delays requested by a Retry-After header",
+			},
+			"s3_endpoint": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Settings for reaching the Garage S3 API, used for bucket lifecycle policies and force_destroy",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"url": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+							Description:  "The URL of the Garage S3 API (e.g., https://s3.example.com). Defaults to the admin API host on port 3900 with the admin API scheme",
+						},
+						"force_path_style": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     true,
+							Description: "Address buckets as url/bucket instead of bucket.url. Disable this if Garage is configured with an S3 root_domain for virtual-hosted-style requests",
+						},
+						"region": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     defaultS3Region,
+							Description: "The S3 region configured in Garage (s3_region), used to sign requests",
+						},
+						"access_key_id": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							RequiredWith: []string{"s3_endpoint.0.secret_access_key"},
+							Description:  "Access key ID used to sign S3 requests. If unset, a temporary key is created for each call and deleted afterwards",
+						},
+						"secret_access_key": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Sensitive:    true,
+							RequiredWith: []string{"s3_endpoint.0.access_key_id"},
+							Description:  "Secret access key used to sign S3 requests",
+						},
+					},
+				},
 			},
 		},
+		// Resources not yet ported to frameworkProvider, see the Development section of README.md.
+		// garage_key, garage_bucket and garage_bucket_key are served by frameworkProvider.
 		ResourcesMap: map[string]*schema.Resource{
-			"garage_key":        resourceGarageKey(),
-			"garage_bucket":     resourceGarageBucket(),
-			"garage_bucket_key": resourceGarageBucketKey(),
+			"garage_bucket_alias":                   resourceGarageBucketAlias(),
+			"garage_bucket_lifecycle_configuration": resourceGarageBucketLifecycleConfiguration(),
+			"garage_bucket_cors_configuration":      resourceGarageBucketCORSConfiguration(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"garage_key":     dataSourceGarageKey(),
+			"garage_keys":    dataSourceGarageKeys(),
+			"garage_bucket":  dataSourceGarageBucket(),
+			"garage_buckets": dataSourceGarageBuckets(),
+		},
+		ConfigureContextFunc: func(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+			return providerConfigure(ctx, d, clients)
 		},
-		ConfigureContextFunc: providerConfigure,
 	}
 }
 
-func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	scheme := d.Get("scheme").(string)
-	host := d.Get("host").(string)
-	token := d.Get("token").(string)
+func providerConfigure(ctx context.Context, d *schema.ResourceData, clients *clientCache) (interface{}, diag.Diagnostics) {
+	settings := providerSettings{
+		Scheme:             d.Get("scheme").(string),
+		Host:               d.Get("host").(string),
+		Token:              d.Get("token").(string),
+		TokenFile:          d.Get("token_file").(string),
+		CACertFile:         d.Get("ca_cert_file").(string),
+		CACertPEM:          d.Get("ca_cert_pem").(string),
+		ClientCert:         d.Get("client_cert").(string),
+		ClientKey:          d.Get("client_key").(string),
+		InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+		SkipHealthCheck:    d.Get("skip_health_check").(bool),
+		MaxRetries:         d.Get("max_retries").(int),
+		RetryMinWait:       d.Get("retry_min_wait").(string),
+		RetryMaxWait:       d.Get("retry_max_wait").(string),
+		S3:                 S3Config{ForcePathStyle: true},
+	}
+
+	if v, ok := d.GetOk("s3_endpoint"); ok && len(v.([]interface{})) > 0 && v.([]interface{})[0] != nil {
+		s3Endpoint := v.([]interface{})[0].(map[string]interface{})
+		settings.S3.Endpoint = s3Endpoint["url"].(string)
+		settings.S3.Region = s3Endpoint["region"].(string)
+		settings.S3.ForcePathStyle = s3Endpoint["force_path_style"].(bool)
+		settings.S3.AccessKeyID = s3Endpoint["access_key_id"].(string)
+		settings.S3.SecretAccessKey = s3Endpoint["secret_access_key"].(string)
+	}
 
-	client, err := NewGarageClient(scheme, host, token)
+	client, diags := clients.configure(ctx, settings)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	return client, diags
+}
+
+// providerSettings is the provider configuration, shared by the SDKv2 and framework providers
+type providerSettings struct {
+	Scheme             string
+	Host               string
+	Token              string
+	TokenFile          string
+	CACertFile         string
+	CACertPEM          string
+	ClientCert         string
+	ClientKey          string
+	InsecureSkipVerify bool
+	SkipHealthCheck    bool
+	MaxRetries         int
+	RetryMinWait       string
+	RetryMaxWait       string
+	S3                 S3Config
+}
+
+// clientCache holds the clients created for the providers muxed by main.go. Both are configured with the
+// same settings, so the client is created and the connection checked once, and the diagnostics returned
+// to both. Entries are keyed on a digest of the settings, which include the admin token and S3 secret.
+type clientCache struct {
+	mu      sync.Mutex
+	entries map[string]*clientCacheEntry
+}
+
+// clientCacheEntry is configured once, without holding the cache lock while the connection is checked
+type clientCacheEntry struct {
+	once   sync.Once
+	client *GarageClient
+	diags  diag.Diagnostics
+}
+
+func newClientCache() *clientCache {
+	return &clientCache{entries: map[string]*clientCacheEntry{}}
+}
+
+// configure returns the client for the settings, creating it and checking the connection on the first call
+func (c *clientCache) configure(ctx context.Context, settings providerSettings) (*GarageClient, diag.Diagnostics) {
+	key, err := settings.digest()
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	c.mu.Lock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &clientCacheEntry{}
+		c.entries[key] = entry
+	}
+
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.client, entry.diags = configureGarageClient(ctx, settings)
+	})
+
+	return entry.client, entry.diags
+}
+
+// configureGarageClient creates a client and checks the connection
+func configureGarageClient(ctx context.Context, settings providerSettings) (*GarageClient, diag.Diagnostics) {
+	// host is optional in the schema so that it can come from the environment, but one of them is required
+	if settings.Host == "" {
+		return nil, diag.Errorf("a host is required: set host or the GARAGE_ADMIN_HOST environment variable")
+	}
+
+	clientConfig, err := settings.clientConfig()
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+
+	client, err := NewGarageClient(clientConfig)
 	if err != nil {
 		return nil, diag.FromErr(fmt.Errorf("failed to create Garage client: %w", err))
 	}
 
-	return client, nil
+	var diags diag.Diagnostics
+
+	// Without the check, capabilities stay unknown and every endpoint is assumed to be allowed
+	if !settings.SkipHealthCheck {
+		diags = client.detectCapabilities(ctx)
+		if diags.HasError() {
+			return nil, diags
+		}
+	}
+
+	return client, diags
+}
+
+// digest identifies the settings without keeping the secrets they contain
+func (s providerSettings) digest() (string, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode provider settings: %w", err)
+	}
+
+	return sha256Hex(b), nil
+}
+
+func (s providerSettings) clientConfig() (ClientConfig, error) {
+	token, err := s.token()
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	retryConfig, err := s.retryConfig()
+	if err != nil {
+		return ClientConfig{}, err
+	}
+
+	return ClientConfig{
+		Scheme: s.Scheme,
+		Host:   s.Host,
+		Token:  token,
+		TLS:    tlsConfig,
+		Retry:  retryConfig,
+		S3:     s.S3,
+	}, nil
+}
+
+// token returns the admin token from token, token_file or, when neither is set, the GARAGE_ADMIN_TOKEN
+// environment variable
+func (s providerSettings) token() (string, error) {
+	if s.TokenFile != "" {
+		data, err := os.ReadFile(s.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token_file: %w", err)
+		}
+
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("token_file %s is empty", s.TokenFile)
+		}
+
+		return token, nil
+	}
+
+	if s.Token != "" {
+		return s.Token, nil
+	}
+
+	if token := os.Getenv("GARAGE_ADMIN_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	return "", fmt.Errorf("an admin token is required: set token, token_file or the GARAGE_ADMIN_TOKEN environment variable")
+}
+
+// tlsConfig collects the TLS settings of the provider
+func (s providerSettings) tlsConfig() (TLSConfig, error) {
+	tlsConfig := TLSConfig{
+		CACertPEM:          []byte(s.CACertPEM),
+		ClientCertPEM:      []byte(s.ClientCert),
+		ClientKeyPEM:       []byte(s.ClientKey),
+		InsecureSkipVerify: s.InsecureSkipVerify,
+	}
+
+	if s.CACertFile != "" {
+		data, err := os.ReadFile(s.CACertFile)
+		if err != nil {
+			return TLSConfig{}, fmt.Errorf("failed to read ca_cert_file: %w", err)
+		}
+
+		tlsConfig.CACertPEM = data
+	}
+
+	return tlsConfig, nil
+}
+
+// retryConfig collects the retry settings of the provider
+func (s providerSettings) retryConfig() (RetryConfig, error) {
+	minWait, err := time.ParseDuration(s.RetryMinWait)
+	if err != nil {
+		return RetryConfig{}, fmt.Errorf("invalid retry_min_wait: %w", err)
+	}
+
+	maxWait, err := time.ParseDuration(s.RetryMaxWait)
+	if err != nil {
+		return RetryConfig{}, fmt.Errorf("invalid retry_max_wait: %w", err)
+	}
+
+	if maxWait < minWait {
+		return RetryConfig{}, fmt.Errorf("retry_max_wait (%s) must not be shorter than retry_min_wait (%s)", maxWait, minWait)
+	}
+
+	return RetryConfig{
+		MaxRetries: s.MaxRetries,
+		MinWait:    minWait,
+		MaxWait:    maxWait,
+	}, nil
+}
+
+func validateDuration(v interface{}, k string) ([]string, []error) {
+	value, ok := v.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+
+	if d, err := time.ParseDuration(value); err != nil || d < 0 {
+		return nil, []error{fmt.Errorf("%s must be a positive duration such as 500ms or 10s, got %q", k, value)}
+	}
+
+	return nil, nil
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestClientCache(t *testing.T) {
+	ctx := context.Background()
+	settings := providerSettings{
+		Scheme:          "http",
+		Host:            "127.0.0.1:3903",
+		Token:           "token",
+		SkipHealthCheck: true,
+		RetryMinWait:    defaultRetryMinWait.String(),
+		RetryMaxWait:    defaultRetryMaxWait.String(),
+	}
+
+	clients := newClientCache()
+
+	var (
+		wg      sync.WaitGroup
+		created [4]*GarageClient
+	)
+
+	for i := range created {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			client, diags := clients.configure(ctx, settings)
+			if diags.HasError() {
+				t.Errorf("configure() diagnostics = %v", diags)
+			}
+
+			created[i] = client
+		}()
+	}
+
+	wg.Wait()
+
+	for _, client := range created[1:] {
+		if client == nil || client != created[0] {
+			t.Fatalf("configure() with the same settings returned different clients")
+		}
+	}
+
+	other := settings
+	other.Token = "other"
+
+	if client, _ := clients.configure(ctx, other); client == created[0] {
+		t.Errorf("configure() with another token returned the same client")
+	}
+
+	if client, _ := newClientCache().configure(ctx, settings); client == created[0] {
+		t.Errorf("configure() on another cache returned the same client")
+	}
+
+	noHost := settings
+	noHost.Host = ""
+
+	if _, diags := clients.configure(ctx, noHost); !diags.HasError() {
+		t.Errorf("configure() without a host succeeded")
+	}
+}
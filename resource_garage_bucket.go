@@ -1,391 +1,717 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/xml"
 	"fmt"
 	"net/http"
-	"strconv"
+	"slices"
 
 	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func resourceGarageBucket() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: resourceGarageBucketCreate,
-		ReadContext:   resourceGarageBucketRead,
-		UpdateContext: resourceGarageBucketUpdate,
-		DeleteContext: resourceGarageBucketDelete,
-		Importer:      &schema.ResourceImporter{},
-		Schema: map[string]*schema.Schema{
-			"id": {
-				Type:        schema.TypeString,
-				Optional:    true,
+// garageBucketResource is served by the framework provider. Version 0 of its schema is the one of the
+// SDKv2 resource it replaces, whose state is upgraded by UpgradeState.
+type garageBucketResource struct {
+	client *GarageClient
+}
+
+var (
+	_ resource.ResourceWithConfigure      = &garageBucketResource{}
+	_ resource.ResourceWithImportState    = &garageBucketResource{}
+	_ resource.ResourceWithModifyPlan     = &garageBucketResource{}
+	_ resource.ResourceWithUpgradeState   = &garageBucketResource{}
+	_ resource.ResourceWithValidateConfig = &garageBucketResource{}
+)
+
+type garageBucketModel struct {
+	ID                         types.String                  `tfsdk:"id"`
+	GlobalAlias                types.String                  `tfsdk:"global_alias"`
+	GlobalAliases              types.Set                     `tfsdk:"global_aliases"`
+	LocalAliases               []garageBucketLocalAliasModel `tfsdk:"local_aliases"`
+	Bytes                      types.Int64                   `tfsdk:"bytes"`
+	Objects                    types.Int64                   `tfsdk:"objects"`
+	ExpirationDays             types.Int64                   `tfsdk:"expiration_days"`
+	LifecycleRule              []lifecycleRuleModel          `tfsdk:"lifecycle_rule"`
+	MaxSize                    types.Int64                   `tfsdk:"max_size"`
+	MaxObjects                 types.Int64                   `tfsdk:"max_objects"`
+	WebsiteAccessEnabled       types.Bool                    `tfsdk:"website_access_enabled"`
+	WebsiteAccessIndexDocument types.String                  `tfsdk:"website_access_index_document"`
+	WebsiteAccessErrorDocument types.String                  `tfsdk:"website_access_error_document"`
+	ForceDestroy               types.Bool                    `tfsdk:"force_destroy"`
+}
+
+type garageBucketLocalAliasModel struct {
+	AccessKeyID types.String `tfsdk:"access_key_id"`
+	Alias       types.String `tfsdk:"alias"`
+}
+
+func NewGarageBucketResource() resource.Resource {
+	return &garageBucketResource{}
+}
+
+func (r *garageBucketResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket"
+}
+
+func (r *garageBucketResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = garageBucketSchema()
+	resp.Schema.Version = 1
+}
+
+// garageBucketSchema is the schema of garage_bucket. Version 0, written by the SDKv2 resource, has the same
+// attribute types, so it is also the prior schema of UpgradeState.
+func garageBucketSchema() schema.Schema {
+	return schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
 				Computed:    true,
-				Description: "The bucket ID (computed if not provided)",
+				Description: "The bucket ID",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"global_alias": schema.StringAttribute{
+				Optional:           true,
+				Computed:           true,
+				DeprecationMessage: "Use global_aliases instead, which manages every global alias of the bucket",
+				Description:        "Global alias for the bucket (this appears as the name in garage bucket list). Other global aliases of the bucket are left untouched",
 			},
-			"global_alias": {
-				Type:        schema.TypeString,
+			"global_aliases": schema.SetAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
-				Description: "Global alias for the bucket (this appears as the name in garage bucket list)",
+				Computed:    true,
+				Description: "All global aliases of the bucket. When set, aliases not listed here are removed. A bucket must keep at least one alias",
 			},
-			"bytes": {
-				Type:        schema.TypeInt,
+			"bytes": schema.Int64Attribute{
 				Computed:    true,
 				Description: "Total number of bytes used by objects in this bucket",
 			},
-			"objects": {
-				Type:        schema.TypeInt,
+			"objects": schema.Int64Attribute{
 				Computed:    true,
 				Description: "Number of objects in this bucket",
 			},
-			"expiration_days": {
-				Type:        schema.TypeInt,
+			"expiration_days": schema.Int64Attribute{
 				Optional:    true,
-				Description: "Number of days after which objects in this bucket will be automatically deleted. Set to 0 to disable expiration.",
+				Description: "Number of days after which objects in this bucket will be automatically deleted. Set to 0 to disable expiration. Use lifecycle_rule for anything more specific",
 			},
-			"max_size": {
-				Type:        schema.TypeInt,
+			"max_size": schema.Int64Attribute{
 				Optional:    true,
-				Description: "Maximum size quota for this bucket",
+				Description: "Maximum size quota for this bucket. No quota applies when unset",
 			},
-			"max_objects": {
-				Type:        schema.TypeInt,
+			"max_objects": schema.Int64Attribute{
 				Optional:    true,
-				Description: "Maximum number of objects quota for this bucket",
+				Description: "Maximum number of objects quota for this bucket. No quota applies when unset",
 			},
-			"website_access_enabled": {
-				Type:        schema.TypeBool,
+			"website_access_enabled": schema.BoolAttribute{
 				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
 				Description: "Whether website access is enabled for this bucket",
 			},
-			"website_access_index_document": {
-				Type:        schema.TypeString,
+			"website_access_index_document": schema.StringAttribute{
 				Optional:    true,
 				Description: "Which document to serve as index page for this bucket",
 			},
-			"website_access_error_document": {
-				Type:        schema.TypeString,
+			"website_access_error_document": schema.StringAttribute{
 				Optional:    true,
 				Description: "Which document to serve as error page for this bucket",
 			},
+			"force_destroy": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Delete all objects and abort incomplete multipart uploads when destroying the bucket, so that a non-empty bucket can be destroyed. These objects are not recoverable.",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"local_aliases": schema.SetNestedBlock{
+				Description: "Aliases of the bucket local to an access key. When set, local aliases not listed here are removed. Without any, local aliases are left untouched",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"access_key_id": schema.StringAttribute{
+							Required:    true,
+							Description: "The access key the alias belongs to",
+						},
+						"alias": schema.StringAttribute{
+							Required:    true,
+							Description: "The alias, only visible to this access key",
+						},
+					},
+				},
+			},
+			"lifecycle_rule": schema.ListNestedBlock{
+				Description:  "Lifecycle rules of the bucket. When set, rules not listed here are removed",
+				NestedObject: lifecycleRuleBlockObject(),
+			},
 		},
 	}
 }
 
-func resourceGarageBucketCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*GarageClient)
-	globalAlias := d.Get("global_alias").(string)
+// UpgradeState reads the state written by the SDKv2 garage_bucket, which stored the zero value of unset
+// arguments where the framework resource stores null. local_aliases was tracked whether set or not, and is
+// now only tracked once set, so it is cleared rather than planned for removal.
+func (r *garageBucketResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	priorSchema := garageBucketSchema()
+
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &priorSchema,
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var data garageBucketModel
+
+				resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				data.GlobalAlias = stringOrNull(data.GlobalAlias.ValueString())
+				data.LocalAliases = nil
+				data.ExpirationDays = int64OrNull(data.ExpirationDays.ValueInt64())
+				data.MaxSize = int64OrNull(data.MaxSize.ValueInt64())
+				data.MaxObjects = int64OrNull(data.MaxObjects.ValueInt64())
+				data.WebsiteAccessIndexDocument = stringOrNull(data.WebsiteAccessIndexDocument.ValueString())
+				data.WebsiteAccessErrorDocument = stringOrNull(data.WebsiteAccessErrorDocument.ValueString())
+
+				for i, rule := range data.LifecycleRule {
+					data.LifecycleRule[i] = lifecycleRuleModel{
+						ID:                                 rule.ID,
+						Enabled:                            rule.Enabled,
+						Prefix:                             stringOrNull(rule.Prefix.ValueString()),
+						ObjectSizeGreaterThan:              int64OrNull(rule.ObjectSizeGreaterThan.ValueInt64()),
+						ObjectSizeLessThan:                 int64OrNull(rule.ObjectSizeLessThan.ValueInt64()),
+						ExpirationDays:                     int64OrNull(rule.ExpirationDays.ValueInt64()),
+						ExpirationDate:                     stringOrNull(rule.ExpirationDate.ValueString()),
+						AbortIncompleteMultipartUploadDays: int64OrNull(rule.AbortIncompleteMultipartUploadDays.ValueInt64()),
+					}
+				}
+
+				resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			},
+		},
+	}
+}
 
-	bucketInfo := garage.NewCreateBucketRequest()
-	if globalAlias != "" {
-		bucketInfo.SetGlobalAlias(globalAlias)
+func (r *garageBucketResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config garageBucketModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	bucket, resp, err := client.Client.BucketAPI.CreateBucket(ctx).CreateBucketRequest(*bucketInfo).Execute()
-	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to create bucket: %w", err))
+	if !config.GlobalAlias.IsNull() && !config.GlobalAliases.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("global_aliases"), "Conflicting configuration", "global_alias and global_aliases cannot both be set")
 	}
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			_ = resp.Body.Close()
-		}
-	}()
 
-	d.SetId(bucket.Id)
+	if !config.ExpirationDays.IsNull() && len(config.LifecycleRule) > 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("expiration_days"), "Conflicting configuration", "expiration_days and lifecycle_rule cannot both be set")
+	}
 
-	if err := d.Set("id", bucket.Id); err != nil {
-		return diag.FromErr(err)
+	validateLifecycleRuleModels(path.Root("lifecycle_rule"), config.LifecycleRule, &resp.Diagnostics)
+}
+
+// ModifyPlan plans the aliases left to the cluster when they are not configured. global_aliases only
+// changes with global_alias, and global_alias stays the same alias as long as the bucket keeps it.
+func (r *garageBucketResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// The resource is created or destroyed
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
 	}
 
-	if err := d.Set("bytes", bucket.Bytes); err != nil {
-		return diag.FromErr(err)
+	var config, plan, state garageBucketModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	if err := d.Set("objects", bucket.Objects); err != nil {
-		return diag.FromErr(err)
+	if config.GlobalAliases.IsNull() && (config.GlobalAlias.IsNull() || config.GlobalAlias.Equal(state.GlobalAlias)) {
+		plan.GlobalAliases = state.GlobalAliases
 	}
 
-	if len(bucket.GlobalAliases) > 0 {
-		if err := d.Set("global_alias", bucket.GlobalAliases[0]); err != nil {
-			return diag.FromErr(err)
+	if config.GlobalAlias.IsNull() && !plan.GlobalAliases.IsUnknown() {
+		var aliases []string
+
+		resp.Diagnostics.Append(plan.GlobalAliases.ElementsAs(ctx, &aliases, false)...)
+
+		plan.GlobalAlias = types.StringUnknown()
+		if slices.Contains(aliases, state.GlobalAlias.ValueString()) || len(aliases) < 2 {
+			plan.GlobalAlias = primaryGlobalAlias(aliases, state.GlobalAlias.ValueString())
 		}
 	}
 
-	// Set quotas if specified
-	quotas := garage.NewApiBucketQuotas()
-	doUpdate := false
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
 
-	if val, ok := d.GetOk("max_size"); ok {
-		quotas.SetMaxSize(int64(val.(int)))
+func (r *garageBucketResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
 
-		doUpdate = true
+	client, ok := req.ProviderData.(*GarageClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *GarageClient, got %T", req.ProviderData))
+		return
 	}
 
-	if val, ok := d.GetOk("max_objects"); ok {
-		quotas.SetMaxObjects(int64(val.(int)))
+	r.client = client
+}
 
-		doUpdate = true
+// configured reports whether Configure received a client. It did not when the provider configuration
+// depends on values that are not known yet, as the framework provider only configures known settings.
+func (r *garageBucketResource) configured(diags *diag.Diagnostics) bool {
+	if r.client == nil {
+		diags.AddError("Unconfigured provider", "The provider configuration is not known yet, so buckets cannot be managed")
+		return false
 	}
 
-	// Set website access if specified
-	websiteAccessEnabled := d.Get("website_access_enabled").(bool)
+	return true
+}
 
-	websiteAccess := garage.NewUpdateBucketWebsiteAccess(websiteAccessEnabled)
-	if websiteAccessEnabled {
-		doUpdate = true
+// allowed reports whether the admin token may call the endpoints an operation needs
+func (r *garageBucketResource) allowed(diags *diag.Diagnostics, feature string, endpoints ...string) bool {
+	if err := r.client.requireEndpoints(feature, endpoints...); err != nil {
+		diags.AddError("Insufficient admin token scope", err.Error())
+		return false
+	}
 
-		if val, ok := d.GetOk("website_access_index_document"); ok {
-			websiteAccess.SetIndexDocument(val.(string))
-		} else {
-			websiteAccess.SetIndexDocument("index.html")
-		}
+	return true
+}
 
-		if val, ok := d.GetOk("website_access_error_document"); ok {
-			websiteAccess.SetErrorDocument(val.(string))
-		}
+func (r *garageBucketResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+func (r *garageBucketResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.configured(&resp.Diagnostics) {
+		return
 	}
 
-	if doUpdate {
-		bucketUpdate := garage.NewUpdateBucketRequestBody()
-		bucketUpdate.SetQuotas(*quotas)
-		bucketUpdate.SetWebsiteAccess(*websiteAccess)
+	var data garageBucketModel
 
-		_, resp, err := client.Client.BucketAPI.UpdateBucket(ctx).Id(bucket.Id).UpdateBucketRequestBody(*bucketUpdate).Execute()
-		if err != nil {
-			return diag.FromErr(fmt.Errorf("failed to update bucket: %w", err))
-		}
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
 
-		defer func() {
-			if resp != nil && resp.Body != nil {
-				_ = resp.Body.Close()
-			}
-		}()
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	// Set expiration policy if specified
-	if expirationDays, ok := d.GetOk("expiration_days"); ok && expirationDays.(int) > 0 {
-		if err := setBucketLifecyclePolicy(ctx, client, bucket.Id, expirationDays.(int)); err != nil {
-			return diag.FromErr(fmt.Errorf("failed to set expiration policy: %w", err))
-		}
+	var globalAliases []string
 
-		if err := d.Set("expiration_days", expirationDays.(int)); err != nil {
-			return diag.FromErr(err)
-		}
+	switch {
+	case !data.GlobalAliases.IsUnknown():
+		resp.Diagnostics.Append(data.GlobalAliases.ElementsAs(ctx, &globalAliases, false)...)
+		slices.Sort(globalAliases)
+	case !data.GlobalAlias.IsUnknown():
+		globalAliases = []string{data.GlobalAlias.ValueString()}
 	}
 
-	return nil
-}
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-func resourceGarageBucketRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*GarageClient)
-	bucketID := d.Id()
+	// Check everything the creation needs first, so it does not stop halfway through
+	endpoints := []string{"CreateBucket", "GetBucketInfo"}
+	if len(globalAliases) > 1 || len(data.LocalAliases) > 0 {
+		endpoints = append(endpoints, "AddBucketAlias")
+	}
 
-	bucket, resp, err := client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
-	if err != nil {
-		if resp != nil && resp.StatusCode == http.StatusNotFound {
-			d.SetId("")
-			return nil
+	if !data.MaxSize.IsNull() || !data.MaxObjects.IsNull() || data.WebsiteAccessEnabled.ValueBool() {
+		endpoints = append(endpoints, "UpdateBucket")
+	}
+
+	if !r.allowed(&resp.Diagnostics, "Creating a bucket", endpoints...) {
+		return
+	}
+
+	if data.ExpirationDays.ValueInt64() > 0 || len(data.LifecycleRule) > 0 {
+		if err := requireLifecycleConfiguration(r.client); err != nil {
+			resp.Diagnostics.AddError("Unsupported Garage version", err.Error())
+			return
 		}
+	}
+
+	bucketInfo := garage.NewCreateBucketRequest()
+	if len(globalAliases) > 0 {
+		bucketInfo.SetGlobalAlias(globalAliases[0])
+	}
 
-		return diag.FromErr(fmt.Errorf("failed to read bucket: %w", err))
+	bucket, httpResp, err := r.client.Client.BucketAPI.CreateBucket(ctx).CreateBucketRequest(*bucketInfo).Execute()
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create bucket", err.Error())
+		return
 	}
 	defer func() {
-		if resp != nil && resp.Body != nil {
-			_ = resp.Body.Close()
+		if httpResp != nil && httpResp.Body != nil {
+			_ = httpResp.Body.Close()
 		}
 	}()
 
-	if err := d.Set("id", bucket.Id); err != nil {
-		return diag.FromErr(err)
-	}
+	// Keep the bucket in state, tainted, if setting it up fails
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), bucket.Id)...)
 
-	if err := d.Set("bytes", bucket.Bytes); err != nil {
-		return diag.FromErr(err)
+	// The bucket is created with a single global alias, add the others afterwards
+	for _, alias := range globalAliases[min(1, len(globalAliases)):] {
+		if err := addBucketAlias(ctx, r.client, bucket.Id, alias, ""); err != nil {
+			resp.Diagnostics.AddError("Failed to add bucket alias", err.Error())
+			return
+		}
 	}
 
-	if err := d.Set("objects", bucket.Objects); err != nil {
-		return diag.FromErr(err)
+	for _, alias := range data.LocalAliases {
+		if err := addBucketAlias(ctx, r.client, bucket.Id, alias.Alias.ValueString(), alias.AccessKeyID.ValueString()); err != nil {
+			resp.Diagnostics.AddError("Failed to add bucket alias", err.Error())
+			return
+		}
 	}
 
-	quotas := bucket.GetQuotas()
-	if val, ok := quotas.GetMaxSizeOk(); ok {
-		if err := d.Set("max_size", val); err != nil {
-			return diag.FromErr(err)
+	if !data.MaxSize.IsNull() || !data.MaxObjects.IsNull() || data.WebsiteAccessEnabled.ValueBool() {
+		bucketUpdate := garage.NewUpdateBucketRequestBody()
+		bucketUpdate.SetQuotas(*bucketQuotas(data))
+		bucketUpdate.SetWebsiteAccess(*bucketWebsiteAccessUpdate(data))
+
+		if err := r.updateBucket(ctx, bucket.Id, bucketUpdate); err != nil {
+			resp.Diagnostics.AddError("Failed to update bucket", err.Error())
+			return
 		}
 	}
 
-	if val, ok := quotas.GetMaxObjectsOk(); ok {
-		if err := d.Set("max_objects", val); err != nil {
-			return diag.FromErr(err)
+	// lifecycle_rule owns the whole lifecycle configuration, expiration_days only its rule
+	if len(data.LifecycleRule) > 0 {
+		rules, err := expandLifecycleRuleModels(data.LifecycleRule)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid lifecycle rule", err.Error())
+			return
+		}
+
+		if err := setBucketLifecycleConfiguration(ctx, r.client, bucket.Id, rules); err != nil {
+			resp.Diagnostics.AddError("Failed to set lifecycle configuration", err.Error())
+			return
+		}
+	} else if expirationDays := int(data.ExpirationDays.ValueInt64()); expirationDays > 0 {
+		if err := putBucketLifecycleRule(ctx, r.client, bucket.Id, expirationDaysRule(expirationDays)); err != nil {
+			resp.Diagnostics.AddError(fmt.Sprintf("Failed to set lifecycle rule %q", expirationDaysRuleID), err.Error())
+			return
 		}
 	}
 
-	var websiteAccess garage.GetBucketInfoWebsiteResponse
-	if bucket.WebsiteAccess {
-		websiteAccess = bucket.GetWebsiteConfig()
+	if err := r.setComputed(ctx, &data, bucket.Id); err != nil {
+		resp.Diagnostics.AddError("Failed to read bucket", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *garageBucketResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if !r.configured(&resp.Diagnostics) || !r.allowed(&resp.Diagnostics, "Reading a bucket", "GetBucketInfo") {
+		return
 	}
 
-	if err := d.Set("website_access_enabled", bucket.WebsiteAccess); err != nil {
-		return diag.FromErr(err)
+	var data garageBucketModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	_, indexDocumentSet := d.GetOk("website_access_index_document")
-	newIndexDocument := websiteAccess.GetIndexDocument()
-	// "nil" is mapped to the "index.html" default when updating the resource
-	// Account for the default here to prevent updating the resource again
-	if indexDocumentSet || newIndexDocument != "index.html" {
-		if err := d.Set("website_access_index_document", newIndexDocument); err != nil {
-			return diag.FromErr(err)
+	bucket, httpResp, err := r.client.Client.BucketAPI.GetBucketInfo(ctx).Id(data.ID.ValueString()).Execute()
+	if err != nil {
+		if httpResp != nil && httpResp.StatusCode == http.StatusNotFound {
+			resp.State.RemoveResource(ctx)
+			return
 		}
+
+		resp.Diagnostics.AddError("Failed to read bucket", err.Error())
+
+		return
 	}
+	defer func() {
+		if httpResp != nil && httpResp.Body != nil {
+			_ = httpResp.Body.Close()
+		}
+	}()
 
-	if err := d.Set("website_access_error_document", websiteAccess.GetErrorDocument()); err != nil {
-		return diag.FromErr(err)
+	data.ID = types.StringValue(bucket.Id)
+	data.Bytes = types.Int64Value(bucket.Bytes)
+	data.Objects = types.Int64Value(bucket.Objects)
+
+	// force_destroy only lives in state, default it so imports don't show a diff
+	if data.ForceDestroy.IsNull() {
+		data.ForceDestroy = types.BoolValue(false)
 	}
 
-	if len(bucket.GlobalAliases) > 0 {
-		if err := d.Set("global_alias", bucket.GlobalAliases[0]); err != nil {
-			return diag.FromErr(err)
-		}
+	quotas := bucket.GetQuotas()
+
+	data.MaxSize = types.Int64Null()
+	if val, ok := quotas.GetMaxSizeOk(); ok && val != nil {
+		data.MaxSize = types.Int64Value(*val)
 	}
 
-	// Read expiration policy if it exists
-	expirationDays, err := getBucketLifecyclePolicy(ctx, client, bucket.Id)
-	if err == nil && expirationDays > 0 {
-		if err := d.Set("expiration_days", expirationDays); err != nil {
-			return diag.FromErr(err)
+	data.MaxObjects = types.Int64Null()
+	if val, ok := quotas.GetMaxObjectsOk(); ok && val != nil {
+		data.MaxObjects = types.Int64Value(*val)
+	}
+
+	data.WebsiteAccessEnabled = types.BoolValue(bucket.WebsiteAccess)
+
+	// Garage forgets the documents when website access is disabled, keep the configured ones
+	if bucket.WebsiteAccess {
+		websiteAccess := bucket.GetWebsiteConfig()
+
+		// "index.html" is the default used when no index document is configured
+		if indexDocument := websiteAccess.GetIndexDocument(); !data.WebsiteAccessIndexDocument.IsNull() || indexDocument != "index.html" {
+			data.WebsiteAccessIndexDocument = stringOrNull(indexDocument)
 		}
-	} else if d.HasChange("expiration_days") {
-		// If expiration_days was removed, clear it
-		if err := d.Set("expiration_days", 0); err != nil {
-			return diag.FromErr(err)
+
+		data.WebsiteAccessErrorDocument = stringOrNull(websiteAccess.GetErrorDocument())
+	}
+
+	globalAliases, diags := types.SetValueFrom(ctx, types.StringType, bucket.GlobalAliases)
+	resp.Diagnostics.Append(diags...)
+
+	data.GlobalAliases = globalAliases
+	// Keep the alias chosen through global_alias if the bucket still has it, so that
+	// buckets with several aliases don't flap between them
+	data.GlobalAlias = primaryGlobalAlias(bucket.GlobalAliases, data.GlobalAlias.ValueString())
+
+	// Local aliases are only tracked once configured
+	if len(data.LocalAliases) > 0 {
+		data.LocalAliases = bucketLocalAliases(bucket.Keys)
+	}
+
+	// Reading the lifecycle configuration needs S3 credentials. Without static ones a temporary
+	// key is created for the call, so only do it for buckets that manage lifecycle rules.
+	// Buckets managing neither leave it to garage_bucket_lifecycle_configuration.
+	hasLifecycleRules := len(data.LifecycleRule) > 0
+
+	if data.ExpirationDays.ValueInt64() > 0 || hasLifecycleRules {
+		rules, err := getBucketLifecycleConfiguration(ctx, r.client, bucket.Id)
+
+		switch {
+		case err != nil:
+			resp.Diagnostics.AddWarning("Failed to read lifecycle configuration",
+				fmt.Sprintf("Could not read the lifecycle configuration of bucket %s, lifecycle drift will not be detected: %s", bucket.Id, err))
+		case hasLifecycleRules:
+			data.LifecycleRule = flattenLifecycleRuleModels(rules)
+		default:
+			// expiration_days only tracks the days of the rule it created
+			data.ExpirationDays = types.Int64Null()
+
+			if rule := findLifecycleRule(rules, expirationDaysRuleID); rule != nil {
+				if rule.Expiration != nil {
+					data.ExpirationDays = types.Int64Value(int64(rule.Expiration.Days))
+				}
+
+				if len(rules) > 1 {
+					resp.Diagnostics.AddWarning("Lifecycle configuration managed twice",
+						fmt.Sprintf("Bucket %s has lifecycle rules besides the %q rule of expiration_days. If a garage_bucket_lifecycle_configuration "+
+							"manages this bucket, remove expiration_days from the garage_bucket, as garage_bucket_lifecycle_configuration overwrites the "+
+							"whole lifecycle configuration and removes the rule.", bucket.Id, expirationDaysRuleID))
+				}
+			}
 		}
 	}
 
-	return nil
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
-func resourceGarageBucketUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*GarageClient)
-	bucketID := d.Id()
-
-	// Handle quota changes
-	doUpdate := false
+func (r *garageBucketResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !r.configured(&resp.Diagnostics) {
+		return
+	}
 
-	var quotas *garage.ApiBucketQuotas
+	var config, data, state garageBucketModel
 
-	if d.HasChanges("max_size", "max_objects") {
-		doUpdate = true
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 
-		quotas = garage.NewApiBucketQuotas()
-		if val, ok := d.GetOk("max_size"); ok {
-			quotas.SetMaxSize(int64(val.(int)))
-		}
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-		if val, ok := d.GetOk("max_objects"); ok {
-			quotas.SetMaxObjects(int64(val.(int)))
-		}
+	bucketID := state.ID.ValueString()
+	aliasesChanged := !data.GlobalAlias.Equal(state.GlobalAlias) || !data.GlobalAliases.Equal(state.GlobalAliases) ||
+		!sameLocalAliases(data.LocalAliases, state.LocalAliases)
+	quotasChanged := !data.MaxSize.Equal(state.MaxSize) || !data.MaxObjects.Equal(state.MaxObjects)
+	websiteChanged := !data.WebsiteAccessEnabled.Equal(state.WebsiteAccessEnabled) ||
+		!data.WebsiteAccessIndexDocument.Equal(state.WebsiteAccessIndexDocument) ||
+		!data.WebsiteAccessErrorDocument.Equal(state.WebsiteAccessErrorDocument)
+	lifecycleChanged := !data.ExpirationDays.Equal(state.ExpirationDays) || !slices.Equal(data.LifecycleRule, state.LifecycleRule)
+
+	endpoints := []string{"GetBucketInfo"}
+	if aliasesChanged {
+		endpoints = append(endpoints, "AddBucketAlias", "RemoveBucketAlias")
 	}
 
-	// Handle website access changes
-	var websiteAccess *garage.UpdateBucketWebsiteAccess
+	if quotasChanged || websiteChanged {
+		endpoints = append(endpoints, "UpdateBucket")
+	}
 
-	if d.HasChanges("website_access_enabled", "website_access_index_document", "website_access_error_document") {
-		doUpdate = true
-		websiteAccessEnabled := d.Get("website_access_enabled").(bool)
+	if !r.allowed(&resp.Diagnostics, "Updating a bucket", endpoints...) {
+		return
+	}
 
-		websiteAccess = garage.NewUpdateBucketWebsiteAccess(websiteAccessEnabled)
-		if websiteAccessEnabled {
-			if val, ok := d.GetOk("website_access_index_document"); ok {
-				websiteAccess.SetIndexDocument(val.(string))
-			} else {
-				websiteAccess.SetIndexDocument("index.html")
-			}
+	if lifecycleChanged {
+		if err := requireLifecycleConfiguration(r.client); err != nil {
+			resp.Diagnostics.AddError("Unsupported Garage version", err.Error())
+			return
+		}
+	}
 
-			if val, ok := d.GetOk("website_access_error_document"); ok {
-				websiteAccess.SetErrorDocument(val.(string))
-			}
+	if aliasesChanged {
+		if err := r.updateAliases(ctx, bucketID, config, data, state); err != nil {
+			resp.Diagnostics.AddError("Failed to update bucket aliases", err.Error())
+			return
 		}
 	}
 
-	if doUpdate {
+	if quotasChanged || websiteChanged {
 		bucketUpdate := garage.NewUpdateBucketRequestBody()
-		if quotas != nil {
-			bucketUpdate.SetQuotas(*quotas)
+		if quotasChanged {
+			bucketUpdate.SetQuotas(*bucketQuotas(data))
 		}
 
-		if websiteAccess != nil {
-			bucketUpdate.SetWebsiteAccess(*websiteAccess)
+		if websiteChanged {
+			bucketUpdate.SetWebsiteAccess(*bucketWebsiteAccessUpdate(data))
 		}
 
-		_, resp, err := client.Client.BucketAPI.UpdateBucket(ctx).Id(bucketID).UpdateBucketRequestBody(*bucketUpdate).Execute()
-		if err != nil {
-			return diag.FromErr(fmt.Errorf("failed to update bucket: %w", err))
+		if err := r.updateBucket(ctx, bucketID, bucketUpdate); err != nil {
+			resp.Diagnostics.AddError("Failed to update bucket", err.Error())
+			return
 		}
+	}
 
-		defer func() {
-			if resp != nil && resp.Body != nil {
-				_ = resp.Body.Close()
+	// lifecycle_rule owns the whole lifecycle configuration, expiration_days only its rule, as the others
+	// may belong to a garage_bucket_lifecycle_configuration
+	if lifecycleChanged {
+		expirationDays := int(data.ExpirationDays.ValueInt64())
+
+		switch {
+		case len(data.LifecycleRule) > 0:
+			rules, err := expandLifecycleRuleModels(data.LifecycleRule)
+			if err != nil {
+				resp.Diagnostics.AddError("Invalid lifecycle rule", err.Error())
+				return
+			}
+
+			if err := setBucketLifecycleConfiguration(ctx, r.client, bucketID, rules); err != nil {
+				resp.Diagnostics.AddError("Failed to update lifecycle configuration", err.Error())
+				return
+			}
+		case len(state.LifecycleRule) > 0:
+			if err := deleteBucketLifecycleConfiguration(ctx, r.client, bucketID); err != nil {
+				resp.Diagnostics.AddError("Failed to remove lifecycle configuration", err.Error())
+				return
 			}
-		}()
-	}
 
-	// Handle expiration policy changes
-	if d.HasChange("expiration_days") {
-		expirationDays := d.Get("expiration_days").(int)
-		if expirationDays > 0 {
-			if err := setBucketLifecyclePolicy(ctx, client, bucketID, expirationDays); err != nil {
-				return diag.FromErr(fmt.Errorf("failed to update expiration policy: %w", err))
+			if expirationDays > 0 {
+				if err := putBucketLifecycleRule(ctx, r.client, bucketID, expirationDaysRule(expirationDays)); err != nil {
+					resp.Diagnostics.AddError(fmt.Sprintf("Failed to set lifecycle rule %q", expirationDaysRuleID), err.Error())
+					return
+				}
 			}
-		} else {
-			// Remove lifecycle policy if expiration_days is 0 or removed
-			if err := deleteBucketLifecyclePolicy(ctx, client, bucketID); err != nil {
-				return diag.FromErr(fmt.Errorf("failed to remove expiration policy: %w", err))
+		case expirationDays > 0:
+			if err := putBucketLifecycleRule(ctx, r.client, bucketID, expirationDaysRule(expirationDays)); err != nil {
+				resp.Diagnostics.AddError(fmt.Sprintf("Failed to set lifecycle rule %q", expirationDaysRuleID), err.Error())
+				return
+			}
+		default:
+			if err := removeBucketLifecycleRule(ctx, r.client, bucketID, expirationDaysRuleID); err != nil {
+				resp.Diagnostics.AddError(fmt.Sprintf("Failed to remove lifecycle rule %q", expirationDaysRuleID), err.Error())
+				return
 			}
 		}
 	}
 
-	return resourceGarageBucketRead(ctx, d, m)
-}
+	if err := r.setComputed(ctx, &data, bucketID); err != nil {
+		resp.Diagnostics.AddError("Failed to read bucket", err.Error())
+		return
+	}
 
-func resourceGarageBucketDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	// Note: Garage API v1 doesn't have a delete bucket endpoint
-	// We'll just remove from state
-	d.SetId("")
-	return nil
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
-// LifecycleConfiguration represents an S3 bucket lifecycle configuration.
-type LifecycleConfiguration struct {
-	XMLName xml.Name `xml:"LifecycleConfiguration"`
-	Rules   []Rule   `xml:"Rule"`
-}
+func (r *garageBucketResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.configured(&resp.Diagnostics) || !r.allowed(&resp.Diagnostics, "Deleting a bucket", "GetBucketInfo", "DeleteBucket") {
+		return
+	}
 
-type Rule struct {
-	ID         string      `xml:"ID"`
-	Status     string      `xml:"Status"`
-	Filter     *Filter     `xml:"Filter,omitempty"`
-	Expiration *Expiration `xml:"Expiration,omitempty"`
-}
+	var data garageBucketModel
 
-type Filter struct {
-	Prefix string `xml:"Prefix"`
-}
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	bucketID := data.ID.ValueString()
+
+	bucket, httpResp, err := r.client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
+	if err != nil {
+		if httpResp != nil && httpResp.StatusCode == http.StatusNotFound {
+			return
+		}
 
-type Expiration struct {
-	Days int `xml:"Days"`
+		resp.Diagnostics.AddError("Failed to read bucket", err.Error())
+
+		return
+	}
+	defer func() {
+		if httpResp != nil && httpResp.Body != nil {
+			_ = httpResp.Body.Close()
+		}
+	}()
+
+	if bucket.Objects > 0 || bucket.Bytes > 0 || bucket.UnfinishedUploads > 0 {
+		if !data.ForceDestroy.ValueBool() {
+			resp.Diagnostics.AddError("Bucket not empty", fmt.Sprintf("bucket %s is not empty (%d objects, %d bytes, %d unfinished uploads); "+
+				"empty it first or set force_destroy = true to delete its contents",
+				bucketID, bucket.Objects, bucket.Bytes, bucket.UnfinishedUploads))
+
+			return
+		}
+
+		err := r.client.withS3Session(ctx, bucket, func(s *s3Session) error {
+			return emptyBucket(ctx, s)
+		})
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to empty bucket", fmt.Sprintf("failed to empty bucket %s: %s", bucketID, err))
+			return
+		}
+	}
+
+	deleteResp, err := r.client.Client.BucketAPI.DeleteBucket(ctx).Id(bucketID).Execute()
+	defer func() {
+		if deleteResp != nil && deleteResp.Body != nil {
+			_ = deleteResp.Body.Close()
+		}
+	}()
+
+	if err != nil && (deleteResp == nil || deleteResp.StatusCode != http.StatusNotFound) {
+		resp.Diagnostics.AddError("Failed to delete bucket", fmt.Sprintf("failed to delete bucket %s: %s", bucketID, err))
+	}
 }
 
-// setBucketLifecyclePolicy sets the lifecycle expiration policy for a bucket using S3-compatible API
-func setBucketLifecyclePolicy(ctx context.Context, client *GarageClient, bucketID string, expirationDays int) error {
-	// Get bucket info to find the bucket alias/name
-	bucket, resp, err := client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
+// setComputed reads the bucket after it was created or updated, and sets the attributes left unknown by the plan
+func (r *garageBucketResource) setComputed(ctx context.Context, data *garageBucketModel, bucketID string) error {
+	bucket, resp, err := r.client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
 	if err != nil {
-		return fmt.Errorf("failed to get bucket info: %w", err)
+		return err
 	}
 	defer func() {
 		if resp != nil && resp.Body != nil {
@@ -393,76 +719,94 @@ func setBucketLifecyclePolicy(ctx context.Context, client *GarageClient, bucketI
 		}
 	}()
 
-	// Get bucket name (alias)
-	bucketName := bucketID
-	if len(bucket.GlobalAliases) > 0 {
-		bucketName = bucket.GlobalAliases[0]
-	}
-
-	// Construct S3 endpoint URL (Garage S3 API typically uses port 3900)
-	// Replace admin port (3903) with S3 port (3900)
-	s3URL := fmt.Sprintf("%s://%s/%s?lifecycle",
-		client.Client.GetConfig().Scheme,
-		replacePort(client.Client.GetConfig().Host, 3900),
-		bucketName)
-
-	// Create lifecycle configuration XML
-	lifecycleConfig := LifecycleConfiguration{
-		Rules: []Rule{
-			{
-				ID:     "expire-after-days",
-				Status: "Enabled",
-				Expiration: &Expiration{
-					Days: expirationDays,
-				},
-			},
-		},
+	data.ID = types.StringValue(bucket.Id)
+	data.Bytes = types.Int64Value(bucket.Bytes)
+	data.Objects = types.Int64Value(bucket.Objects)
+
+	if data.GlobalAliases.IsUnknown() {
+		globalAliases, diags := types.SetValueFrom(ctx, types.StringType, bucket.GlobalAliases)
+		if diags.HasError() {
+			return fmt.Errorf("failed to set global_aliases: %v", diags)
+		}
+
+		data.GlobalAliases = globalAliases
 	}
 
-	xmlData, err := xml.MarshalIndent(lifecycleConfig, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal lifecycle config: %w", err)
+	if data.GlobalAlias.IsUnknown() {
+		data.GlobalAlias = primaryGlobalAlias(bucket.GlobalAliases, "")
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s3URL, bytes.NewReader(xmlData))
+	return nil
+}
+
+// updateAliases adds and removes aliases to match the configuration. Aliases are added before any is
+// removed, as Garage refuses to remove the last alias of a bucket.
+func (r *garageBucketResource) updateAliases(ctx context.Context, bucketID string, config, data, state garageBucketModel) error {
+	bucket, resp, err := r.client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to read bucket: %w", err)
 	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	var add, remove []bucketAlias
+
+	switch {
+	case !config.GlobalAliases.IsNull():
+		var aliases []string
+		if diags := data.GlobalAliases.ElementsAs(ctx, &aliases, false); diags.HasError() {
+			return fmt.Errorf("failed to read global_aliases: %v", diags)
+		}
 
-	req.Header.Set("Content-Type", "application/xml")
-	// Use admin token for authorization (Garage may accept this for S3 API too)
-	if authHeader, ok := client.Client.GetConfig().DefaultHeader["Authorization"]; ok {
-		req.Header.Set("Authorization", authHeader)
+		add, remove = bucketAliasChanges(globalBucketAliases(bucket.GlobalAliases), globalBucketAliases(aliases))
+	case !config.GlobalAlias.IsNull() && !data.GlobalAlias.Equal(state.GlobalAlias):
+		if !slices.Contains(bucket.GlobalAliases, data.GlobalAlias.ValueString()) {
+			add = append(add, bucketAlias{alias: data.GlobalAlias.ValueString()})
+		}
+
+		if slices.Contains(bucket.GlobalAliases, state.GlobalAlias.ValueString()) {
+			remove = append(remove, bucketAlias{alias: state.GlobalAlias.ValueString()})
+		}
 	}
 
-	// Execute request
-	httpClient := &http.Client{}
+	if len(data.LocalAliases) > 0 {
+		var configured []bucketAlias
+		for _, alias := range data.LocalAliases {
+			configured = append(configured, bucketAlias{accessKeyID: alias.AccessKeyID.ValueString(), alias: alias.Alias.ValueString()})
+		}
+
+		var current []bucketAlias
+		for _, alias := range bucketLocalAliases(bucket.Keys) {
+			current = append(current, bucketAlias{accessKeyID: alias.AccessKeyID.ValueString(), alias: alias.Alias.ValueString()})
+		}
 
-	resp, err = httpClient.Do(req)
-	if err != nil || resp == nil {
-		return fmt.Errorf("failed to execute request: %w", err)
+		addLocal, removeLocal := bucketAliasChanges(current, configured)
+		add = append(add, addLocal...)
+		remove = append(remove, removeLocal...)
 	}
 
-	defer func() {
-		if resp.Body != nil {
-			_ = resp.Body.Close()
+	for _, alias := range add {
+		if err := addBucketAlias(ctx, r.client, bucketID, alias.alias, alias.accessKeyID); err != nil {
+			return err
 		}
-	}()
+	}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	for _, alias := range remove {
+		if err := removeBucketAlias(ctx, r.client, bucketID, alias.alias, alias.accessKeyID); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// getBucketLifecyclePolicy retrieves the lifecycle expiration policy for a bucket
-func getBucketLifecyclePolicy(ctx context.Context, client *GarageClient, bucketID string) (int, error) {
-	// Get bucket info to find the bucket alias/name
-	bucket, resp, err := client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
+func (r *garageBucketResource) updateBucket(ctx context.Context, bucketID string, bucketUpdate *garage.UpdateBucketRequestBody) error {
+	_, resp, err := r.client.Client.BucketAPI.UpdateBucket(ctx).Id(bucketID).UpdateBucketRequestBody(*bucketUpdate).Execute()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get bucket info: %w", err)
+		return err
 	}
 	defer func() {
 		if resp != nil && resp.Body != nil {
@@ -470,131 +814,330 @@ func getBucketLifecyclePolicy(ctx context.Context, client *GarageClient, bucketI
 		}
 	}()
 
-	// Get bucket name (alias)
-	bucketName := bucketID
-	if len(bucket.GlobalAliases) > 0 {
-		bucketName = bucket.GlobalAliases[0]
+	return nil
+}
+
+// bucketQuotas returns the quotas of the bucket, a quota being removed when unset
+func bucketQuotas(data garageBucketModel) *garage.ApiBucketQuotas {
+	quotas := garage.NewApiBucketQuotas()
+
+	if !data.MaxSize.IsNull() {
+		quotas.SetMaxSize(data.MaxSize.ValueInt64())
 	}
 
-	// Construct S3 endpoint URL
-	s3URL := fmt.Sprintf("%s://%s/%s?lifecycle",
-		client.Client.GetConfig().Scheme,
-		replacePort(client.Client.GetConfig().Host, 3900),
-		bucketName)
+	if !data.MaxObjects.IsNull() {
+		quotas.SetMaxObjects(data.MaxObjects.ValueInt64())
+	}
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s3URL, nil)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %w", err)
+	return quotas
+}
+
+// bucketWebsiteAccessUpdate returns the website access of the bucket, serving index.html unless another index document is set
+func bucketWebsiteAccessUpdate(data garageBucketModel) *garage.UpdateBucketWebsiteAccess {
+	websiteAccess := garage.NewUpdateBucketWebsiteAccess(data.WebsiteAccessEnabled.ValueBool())
+	if !data.WebsiteAccessEnabled.ValueBool() {
+		return websiteAccess
 	}
 
-	// Use admin token for authorization
-	if authHeader, ok := client.Client.GetConfig().DefaultHeader["Authorization"]; ok {
-		req.Header.Set("Authorization", authHeader)
+	websiteAccess.SetIndexDocument(stringWithDefault(data.WebsiteAccessIndexDocument, "index.html"))
+
+	if !data.WebsiteAccessErrorDocument.IsNull() {
+		websiteAccess.SetErrorDocument(data.WebsiteAccessErrorDocument.ValueString())
 	}
 
-	// Execute request
-	httpClient := &http.Client{}
+	return websiteAccess
+}
 
-	resp, err = httpClient.Do(req)
-	if err != nil || resp == nil {
-		return 0, fmt.Errorf("failed to execute request: %w", err)
+// primaryGlobalAlias returns current if the bucket still has this alias, or else its first one
+func primaryGlobalAlias(aliases []string, current string) types.String {
+	switch {
+	case current != "" && slices.Contains(aliases, current):
+		return types.StringValue(current)
+	case len(aliases) > 0:
+		return types.StringValue(aliases[0])
+	default:
+		return types.StringNull()
 	}
+}
 
-	defer func() {
-		if resp.Body != nil {
-			_ = resp.Body.Close()
+func bucketLocalAliases(keys []garage.GetBucketInfoKey) []garageBucketLocalAliasModel {
+	var aliases []garageBucketLocalAliasModel
+
+	for _, key := range keys {
+		for _, alias := range key.BucketLocalAliases {
+			aliases = append(aliases, garageBucketLocalAliasModel{
+				AccessKeyID: types.StringValue(key.AccessKeyId),
+				Alias:       types.StringValue(alias),
+			})
 		}
-	}()
+	}
 
-	if resp.StatusCode == http.StatusNotFound {
-		return 0, nil // No lifecycle policy set
+	return aliases
+}
+
+// sameLocalAliases reports whether a and b hold the same aliases, which come from sets in no particular order
+func sameLocalAliases(a, b []garageBucketLocalAliasModel) bool {
+	if len(a) != len(b) {
+		return false
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	for _, alias := range a {
+		if !slices.Contains(b, alias) {
+			return false
+		}
 	}
 
-	// Parse XML response
-	var lifecycleConfig LifecycleConfiguration
-	if err := xml.NewDecoder(resp.Body).Decode(&lifecycleConfig); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+	return true
+}
+
+// bucketAlias is an alias of a bucket, local to accessKeyID if set and global otherwise
+type bucketAlias struct {
+	accessKeyID string
+	alias       string
+}
+
+func globalBucketAliases(aliases []string) []bucketAlias {
+	result := make([]bucketAlias, 0, len(aliases))
+	for _, alias := range aliases {
+		result = append(result, bucketAlias{alias: alias})
 	}
 
-	// Extract expiration days from first rule
-	if len(lifecycleConfig.Rules) > 0 && lifecycleConfig.Rules[0].Expiration != nil {
-		return lifecycleConfig.Rules[0].Expiration.Days, nil
+	return result
+}
+
+// bucketAliasChanges returns the aliases to add and remove for a bucket to have the wanted aliases
+func bucketAliasChanges(current, wanted []bucketAlias) (add, remove []bucketAlias) {
+	for _, alias := range wanted {
+		if !slices.Contains(current, alias) {
+			add = append(add, alias)
+		}
 	}
 
-	return 0, nil
+	for _, alias := range current {
+		if !slices.Contains(wanted, alias) {
+			remove = append(remove, alias)
+		}
+	}
+
+	return add, remove
 }
 
-// deleteBucketLifecyclePolicy removes the lifecycle policy from a bucket
-func deleteBucketLifecyclePolicy(ctx context.Context, client *GarageClient, bucketID string) error {
-	// Get bucket info to find the bucket alias/name
-	bucket, resp, err := client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
+// addBucketAlias adds a global alias to a bucket, or a local one if accessKeyID is set
+func addBucketAlias(ctx context.Context, client *GarageClient, bucketID, alias, accessKeyID string) error {
+	request := garage.AddBucketAliasRequest{BucketId: bucketID}
+	if accessKeyID != "" {
+		request.SetLocalAlias(alias)
+		request.SetAccessKeyId(accessKeyID)
+	} else {
+		request.SetGlobalAlias(alias)
+	}
+
+	_, resp, err := client.Client.BucketAliasAPI.AddBucketAlias(ctx).AddBucketAliasRequest(request).Execute()
 	if err != nil {
-		return fmt.Errorf("failed to get bucket info: %w", err)
+		return fmt.Errorf("failed to add alias %q to bucket: %w", alias, err)
 	}
 	defer func() {
-		if resp.Body != nil {
+		if resp != nil && resp.Body != nil {
 			_ = resp.Body.Close()
 		}
 	}()
 
-	// Get bucket name (alias)
-	bucketName := bucketID
-	if len(bucket.GlobalAliases) > 0 {
-		bucketName = bucket.GlobalAliases[0]
-	}
+	return nil
+}
 
-	// Construct S3 endpoint URL
-	s3URL := fmt.Sprintf("%s://%s/%s?lifecycle",
-		client.Client.GetConfig().Scheme,
-		replacePort(client.Client.GetConfig().Host, 3900),
-		bucketName)
+// removeBucketAlias removes a global alias from a bucket, or a local one if accessKeyID is set
+func removeBucketAlias(ctx context.Context, client *GarageClient, bucketID, alias, accessKeyID string) error {
+	request := garage.RemoveBucketAliasRequest{BucketId: bucketID}
+	if accessKeyID != "" {
+		request.SetLocalAlias(alias)
+		request.SetAccessKeyId(accessKeyID)
+	} else {
+		request.SetGlobalAlias(alias)
+	}
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s3URL, nil)
+	_, resp, err := client.Client.BucketAliasAPI.RemoveBucketAlias(ctx).RemoveBucketAliasRequest(request).Execute()
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to remove alias %q from bucket: %w", alias, err)
 	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
 
-	// Use admin token for authorization
-	if authHeader, ok := client.Client.GetConfig().DefaultHeader["Authorization"]; ok {
-		req.Header.Set("Authorization", authHeader)
-	}
+	return nil
+}
 
-	// Execute request
-	httpClient := &http.Client{}
+// withBucketS3Session looks up a bucket by ID and runs fn with an S3 session on it
+func withBucketS3Session(ctx context.Context, client *GarageClient, bucketID string, fn func(s *s3Session) error) error {
+	if err := client.requireEndpoints("Accessing a bucket on the S3 API", "GetBucketInfo"); err != nil {
+		return err
+	}
 
-	resp, err = httpClient.Do(req)
+	bucket, resp, err := client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
 	if err != nil {
-		return fmt.Errorf("failed to execute request: %w", err)
+		return fmt.Errorf("failed to get bucket info: %w", err)
 	}
-
 	defer func() {
-		if resp.Body != nil {
+		if resp != nil && resp.Body != nil {
 			_ = resp.Body.Close()
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	return client.withS3Session(ctx, bucket, fn)
+}
+
+func stringOrNull(s string) types.String {
+	if s == "" {
+		return types.StringNull()
 	}
 
-	return nil
+	return types.StringValue(s)
 }
 
-// replacePort replaces the port in a host string (e.g., "127.0.0.1:3903" -> "127.0.0.1:3900")
-func replacePort(host string, newPort int) string {
-	// Simple implementation - if host contains a port, replace it
-	// Otherwise, append the new port
-	for i := len(host) - 1; i >= 0; i-- {
-		if host[i] == ':' {
-			return host[:i+1] + strconv.Itoa(newPort)
+func int64OrNull(v int64) types.Int64 {
+	if v == 0 {
+		return types.Int64Null()
+	}
+
+	return types.Int64Value(v)
+}
+
+type lifecycleRuleModel struct {
+	ID                                 types.String `tfsdk:"id"`
+	Enabled                            types.Bool   `tfsdk:"enabled"`
+	Prefix                             types.String `tfsdk:"prefix"`
+	ObjectSizeGreaterThan              types.Int64  `tfsdk:"object_size_greater_than"`
+	ObjectSizeLessThan                 types.Int64  `tfsdk:"object_size_less_than"`
+	ExpirationDays                     types.Int64  `tfsdk:"expiration_days"`
+	ExpirationDate                     types.String `tfsdk:"expiration_date"`
+	AbortIncompleteMultipartUploadDays types.Int64  `tfsdk:"abort_incomplete_multipart_upload_days"`
+}
+
+// lifecycleRuleBlockObject is the framework counterpart of lifecycleRuleResource, validated by validateLifecycleRuleModels
+func lifecycleRuleBlockObject() schema.NestedBlockObject {
+	return schema.NestedBlockObject{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Required:    true,
+				Description: "Unique identifier of the rule",
+			},
+			"enabled": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(true),
+				Description: "Whether the rule is applied",
+			},
+			"prefix": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only apply the rule to objects with a key starting with this prefix",
+			},
+			"object_size_greater_than": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Only apply the rule to objects larger than this many bytes",
+			},
+			"object_size_less_than": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Only apply the rule to objects smaller than this many bytes",
+			},
+			"expiration_days": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Delete objects this many days after they were created",
+			},
+			"expiration_date": schema.StringAttribute{
+				Optional:    true,
+				Description: "Delete objects from this date on, as an RFC3339 timestamp at midnight UTC such as 2030-01-01T00:00:00Z",
+			},
+			"abort_incomplete_multipart_upload_days": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Abort multipart uploads this many days after they were started",
+			},
+		},
+	}
+}
+
+// validateLifecycleRuleModels checks the rules as lifecycleRuleResource does. Rules Garage would refuse are
+// only rejected once every value is known.
+func validateLifecycleRuleModels(p path.Path, rules []lifecycleRuleModel, diags *diag.Diagnostics) {
+	known := true
+
+	for i, rule := range rules {
+		rulePath := p.AtListIndex(i)
+
+		if id := rule.ID.ValueString(); !rule.ID.IsUnknown() && (len(id) < 1 || len(id) > 255) {
+			diags.AddAttributeError(rulePath.AtName("id"), "Invalid lifecycle rule", "id must be between 1 and 255 characters long")
 		}
+
+		for name, value := range map[string]types.Int64{
+			"object_size_greater_than": rule.ObjectSizeGreaterThan,
+			"object_size_less_than":    rule.ObjectSizeLessThan,
+		} {
+			if value.ValueInt64() < 0 {
+				diags.AddAttributeError(rulePath.AtName(name), "Invalid lifecycle rule", fmt.Sprintf("%s must be at least 0", name))
+			}
+		}
+
+		for name, value := range map[string]types.Int64{
+			"expiration_days":                        rule.ExpirationDays,
+			"abort_incomplete_multipart_upload_days": rule.AbortIncompleteMultipartUploadDays,
+		} {
+			if !value.IsNull() && !value.IsUnknown() && value.ValueInt64() < 1 {
+				diags.AddAttributeError(rulePath.AtName(name), "Invalid lifecycle rule", fmt.Sprintf("%s must be at least 1", name))
+			}
+		}
+
+		known = known && !rule.ID.IsUnknown() && !rule.Enabled.IsUnknown() && !rule.Prefix.IsUnknown() &&
+			!rule.ObjectSizeGreaterThan.IsUnknown() && !rule.ObjectSizeLessThan.IsUnknown() && !rule.ExpirationDays.IsUnknown() &&
+			!rule.ExpirationDate.IsUnknown() && !rule.AbortIncompleteMultipartUploadDays.IsUnknown()
+	}
+
+	if !known || diags.HasError() {
+		return
+	}
+
+	if _, err := expandLifecycleRuleModels(rules); err != nil {
+		diags.AddAttributeError(p, "Invalid lifecycle rule", err.Error())
+	}
+}
+
+// expandLifecycleRuleModels converts lifecycle_rule blocks into S3 rules, rejecting rules Garage would refuse
+func expandLifecycleRuleModels(rules []lifecycleRuleModel) ([]Rule, error) {
+	raw := make([]interface{}, 0, len(rules))
+
+	for _, rule := range rules {
+		raw = append(raw, map[string]interface{}{
+			"id":                                     rule.ID.ValueString(),
+			"enabled":                                rule.Enabled.IsNull() || rule.Enabled.ValueBool(),
+			"prefix":                                 rule.Prefix.ValueString(),
+			"object_size_greater_than":               int(rule.ObjectSizeGreaterThan.ValueInt64()),
+			"object_size_less_than":                  int(rule.ObjectSizeLessThan.ValueInt64()),
+			"expiration_days":                        int(rule.ExpirationDays.ValueInt64()),
+			"expiration_date":                        rule.ExpirationDate.ValueString(),
+			"abort_incomplete_multipart_upload_days": int(rule.AbortIncompleteMultipartUploadDays.ValueInt64()),
+		})
+	}
+
+	return expandLifecycleRules(raw)
+}
+
+// flattenLifecycleRuleModels converts S3 rules into lifecycle_rule blocks, leaving unset values null
+func flattenLifecycleRuleModels(rules []Rule) []lifecycleRuleModel {
+	result := make([]lifecycleRuleModel, 0, len(rules))
+
+	for _, v := range flattenLifecycleRules(rules) {
+		block := v.(map[string]interface{})
+
+		result = append(result, lifecycleRuleModel{
+			ID:                                 types.StringValue(block["id"].(string)),
+			Enabled:                            types.BoolValue(block["enabled"].(bool)),
+			Prefix:                             stringOrNull(block["prefix"].(string)),
+			ObjectSizeGreaterThan:              int64OrNull(int64(block["object_size_greater_than"].(int))),
+			ObjectSizeLessThan:                 int64OrNull(int64(block["object_size_less_than"].(int))),
+			ExpirationDays:                     int64OrNull(int64(block["expiration_days"].(int))),
+			ExpirationDate:                     stringOrNull(block["expiration_date"].(string)),
+			AbortIncompleteMultipartUploadDays: int64OrNull(int64(block["abort_incomplete_multipart_upload_days"].(int))),
+		})
 	}
 
-	return host + ":" + strconv.Itoa(newPort)
+	return result
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceGarageBucketAlias() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceGarageBucketAliasCreate,
+		ReadContext:   resourceGarageBucketAliasRead,
+		DeleteContext: resourceGarageBucketAliasDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: func(ctx context.Context, d *schema.ResourceData, meta any) ([]*schema.ResourceData, error) {
+				parts := strings.Split(d.Id(), "/")
+
+				var bucketID, keyID, alias string
+
+				switch {
+				case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+					bucketID, alias = parts[0], parts[1]
+				case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+					bucketID, keyID, alias = parts[0], parts[1], parts[2]
+				default:
+					return nil, fmt.Errorf("unexpected format of ID (%s), expected bucket_id/alias or bucket_id/access_key_id/alias", d.Id())
+				}
+
+				if err := d.Set("bucket_id", bucketID); err != nil {
+					return nil, err
+				}
+
+				if err := d.Set("access_key_id", keyID); err != nil {
+					return nil, err
+				}
+
+				if err := d.Set("alias", alias); err != nil {
+					return nil, err
+				}
+
+				d.SetId(bucketAliasID(bucketID, keyID, alias))
+
+				return []*schema.ResourceData{d}, nil
+			},
+		},
+		Schema: map[string]*schema.Schema{
+			"bucket_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The bucket ID",
+			},
+			"alias": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The alias to give the bucket",
+			},
+			"access_key_id": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The access key for a local alias, only visible to this key. Leave unset for a global alias",
+			},
+		},
+	}
+}
+
+func resourceGarageBucketAliasCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+	bucketID := d.Get("bucket_id").(string)
+	alias := d.Get("alias").(string)
+	keyID := d.Get("access_key_id").(string)
+
+	if err := client.requireEndpoints("Creating a bucket alias", bucketAliasEndpoints(keyID, "AddBucketAlias")...); err != nil {
+		return diag.FromErr(err)
+	}
+
+	currentBucketID, err := bucketAliasTarget(ctx, client, alias, keyID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if currentBucketID != "" && currentBucketID != bucketID {
+		return diag.Errorf("alias %q already points at bucket %s; remove it from that bucket before assigning it to %s", alias, currentBucketID, bucketID)
+	}
+
+	if currentBucketID == "" {
+		if err := addBucketAlias(ctx, client, bucketID, alias, keyID); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	d.SetId(bucketAliasID(bucketID, keyID, alias))
+
+	return resourceGarageBucketAliasRead(ctx, d, m)
+}
+
+func resourceGarageBucketAliasRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+	bucketID := d.Get("bucket_id").(string)
+	alias := d.Get("alias").(string)
+	keyID := d.Get("access_key_id").(string)
+
+	if err := client.requireEndpoints("Reading a bucket alias", "GetBucketInfo"); err != nil {
+		return diag.FromErr(err)
+	}
+
+	bucket, resp, err := client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			d.SetId("")
+			return nil
+		}
+
+		return diag.FromErr(fmt.Errorf("failed to read bucket: %w", err))
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	if keyID == "" {
+		if !slices.Contains(bucket.GlobalAliases, alias) {
+			d.SetId("")
+		}
+
+		return nil
+	}
+
+	for _, key := range bucket.Keys {
+		if key.AccessKeyId == keyID && slices.Contains(key.BucketLocalAliases, alias) {
+			return nil
+		}
+	}
+
+	// The alias was removed or points at another bucket
+	d.SetId("")
+
+	return nil
+}
+
+func resourceGarageBucketAliasDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+	bucketID := d.Get("bucket_id").(string)
+	alias := d.Get("alias").(string)
+	keyID := d.Get("access_key_id").(string)
+
+	if err := client.requireEndpoints("Deleting a bucket alias", bucketAliasEndpoints(keyID, "RemoveBucketAlias")...); err != nil {
+		return diag.FromErr(err)
+	}
+
+	currentBucketID, err := bucketAliasTarget(ctx, client, alias, keyID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// Only remove the alias if it still points at this bucket
+	if currentBucketID == bucketID {
+		if err := removeBucketAlias(ctx, client, bucketID, alias, keyID); err != nil {
+			return diag.FromErr(fmt.Errorf("%w (Garage refuses to remove the last alias of a bucket, give it another alias first)", err))
+		}
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+// bucketAliasTarget returns the ID of the bucket an alias points at, or "" if the alias is free
+func bucketAliasTarget(ctx context.Context, client *GarageClient, alias, keyID string) (string, error) {
+	if keyID != "" {
+		return findBucketIDByLocalAlias(ctx, client, keyID, alias)
+	}
+
+	bucket, resp, err := client.Client.BucketAPI.GetBucketInfo(ctx).GlobalAlias(alias).Execute()
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to look up alias %q: %w", alias, err)
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	return bucket.Id, nil
+}
+
+// bucketAliasEndpoints returns the endpoints used to change an alias: the one given, and the one
+// bucketAliasTarget looks the alias up with
+func bucketAliasEndpoints(keyID, endpoint string) []string {
+	if keyID != "" {
+		return []string{"GetKeyInfo", endpoint}
+	}
+
+	return []string{"GetBucketInfo", endpoint}
+}
+
+func bucketAliasID(bucketID, keyID, alias string) string {
+	if keyID == "" {
+		return fmt.Sprintf("%s/%s", bucketID, alias)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", bucketID, keyID, alias)
+}
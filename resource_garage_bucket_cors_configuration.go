@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceGarageBucketCORSConfiguration() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceGarageBucketCORSConfigurationCreate,
+		ReadContext:   resourceGarageBucketCORSConfigurationRead,
+		UpdateContext: resourceGarageBucketCORSConfigurationUpdate,
+		DeleteContext: resourceGarageBucketCORSConfigurationDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			"bucket_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The bucket ID",
+			},
+			"cors_rule": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				MaxItems:    100,
+				Description: "The CORS rules of the bucket. Rules not listed here are removed",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Unique identifier of the rule",
+						},
+						"allowed_origins": {
+							Type:        schema.TypeSet,
+							Required:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Origins allowed to make cross-origin requests, such as https://example.com or *",
+						},
+						"allowed_methods": {
+							Type:     schema.TypeSet,
+							Required: true,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validation.StringInSlice([]string{"GET", "PUT", "POST", "DELETE", "HEAD"}, false),
+							},
+							Description: "HTTP methods allowed for cross-origin requests: GET, PUT, POST, DELETE or HEAD",
+						},
+						"allowed_headers": {
+							Type:        schema.TypeSet,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Headers allowed in preflight requests, such as Content-Type or *",
+						},
+						"expose_headers": {
+							Type:        schema.TypeSet,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Response headers browsers may expose to scripts, such as ETag",
+						},
+						"max_age_seconds": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntAtLeast(0),
+							Description:  "How long browsers may cache the preflight response",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceGarageBucketCORSConfigurationCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+	bucketID := d.Get("bucket_id").(string)
+
+	if err := setBucketCORSConfiguration(ctx, client, bucketID, expandCORSRules(d.Get("cors_rule").([]interface{}))); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to set CORS configuration: %w", err))
+	}
+
+	d.SetId(bucketID)
+
+	return resourceGarageBucketCORSConfigurationRead(ctx, d, m)
+}
+
+func resourceGarageBucketCORSConfigurationRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+	bucketID := d.Id()
+
+	exists, err := bucketExists(ctx, client, bucketID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !exists {
+		d.SetId("")
+		return nil
+	}
+
+	rules, err := getBucketCORSConfiguration(ctx, client, bucketID)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to read CORS configuration: %w", err))
+	}
+
+	if len(rules) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	if err := d.Set("bucket_id", bucketID); err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := d.Set("cors_rule", flattenCORSRules(rules)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return nil
+}
+
+func resourceGarageBucketCORSConfigurationUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+
+	if err := setBucketCORSConfiguration(ctx, client, d.Id(), expandCORSRules(d.Get("cors_rule").([]interface{}))); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to update CORS configuration: %w", err))
+	}
+
+	return resourceGarageBucketCORSConfigurationRead(ctx, d, m)
+}
+
+func resourceGarageBucketCORSConfigurationDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+	bucketID := d.Id()
+
+	exists, err := bucketExists(ctx, client, bucketID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if exists {
+		if err := deleteBucketCORSConfiguration(ctx, client, bucketID); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to remove CORS configuration: %w", err))
+		}
+	}
+
+	d.SetId("")
+
+	return nil
+}
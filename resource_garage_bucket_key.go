@@ -7,169 +7,355 @@ import (
 	"strings"
 
 	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func resourceGarageBucketKey() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: resourceGarageBucketKeyCreate,
-		ReadContext:   resourceGarageBucketKeyRead,
-		UpdateContext: resourceGarageBucketKeyUpdate,
-		DeleteContext: resourceGarageBucketKeyDelete,
-		Importer: &schema.ResourceImporter{
-			StateContext: func(ctx context.Context, d *schema.ResourceData, meta any) ([]*schema.ResourceData, error) {
-				parts := strings.SplitN(d.Id(), "/", 2)
-
-				if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-					return nil, fmt.Errorf("unexpected format of ID (%s), expected bucket_id/access_key_id", d.Id())
-				}
+// garageBucketKeyResource is served by the framework provider. Version 0 of its schema is the one of the
+// SDKv2 resource it replaces, whose state is upgraded by UpgradeState.
+type garageBucketKeyResource struct {
+	client *GarageClient
+}
 
-				if err := d.Set("bucket_id", parts[0]); err != nil {
-					return nil, err
-				}
+var (
+	_ resource.ResourceWithConfigure    = &garageBucketKeyResource{}
+	_ resource.ResourceWithImportState  = &garageBucketKeyResource{}
+	_ resource.ResourceWithModifyPlan   = &garageBucketKeyResource{}
+	_ resource.ResourceWithUpgradeState = &garageBucketKeyResource{}
+)
 
-				if err := d.Set("access_key_id", parts[1]); err != nil {
-					return nil, err
-				}
+type garageBucketKeyModel struct {
+	ID          types.String `tfsdk:"id"`
+	BucketID    types.String `tfsdk:"bucket_id"`
+	AccessKeyID types.String `tfsdk:"access_key_id"`
+	Read        types.Bool   `tfsdk:"read"`
+	Write       types.Bool   `tfsdk:"write"`
+	Owner       types.Bool   `tfsdk:"owner"`
+}
 
-				d.SetId(fmt.Sprintf("%s/%s", parts[0], parts[1]))
+func NewGarageBucketKeyResource() resource.Resource {
+	return &garageBucketKeyResource{}
+}
 
-				return []*schema.ResourceData{d}, nil
-			},
+func (r *garageBucketKeyResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bucket_key"
+}
+
+func (r *garageBucketKeyResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version:    1,
+		Attributes: garageBucketKeyAttributes(),
+	}
+}
+
+func garageBucketKeyAttributes() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"id": schema.StringAttribute{
+			Computed:    true,
+			Description: "The bucket ID and access key ID, as bucket_id/access_key_id",
 		},
-		Schema: map[string]*schema.Schema{
-			"bucket_id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "The bucket ID",
-			},
-			"access_key_id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "The access key ID",
+		"bucket_id": schema.StringAttribute{
+			Required:    true,
+			Description: "The bucket ID",
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.RequiresReplace(),
 			},
-			"read": {
-				Type:        schema.TypeBool,
-				Required:    true,
-				Description: "Grant read permission",
-			},
-			"write": {
-				Type:        schema.TypeBool,
-				Required:    true,
-				Description: "Grant write permission",
-			},
-			"owner": {
-				Type:        schema.TypeBool,
-				Required:    true,
-				Description: "Grant owner permission",
+		},
+		"access_key_id": schema.StringAttribute{
+			Required:    true,
+			Description: "The access key ID. Changing it, as when garage_key rotates the key, grants the permissions to the new key and removes those of the old key, unless it expires",
+		},
+		"read": schema.BoolAttribute{
+			Required:    true,
+			Description: "Grant read permission",
+		},
+		"write": schema.BoolAttribute{
+			Required:    true,
+			Description: "Grant write permission",
+		},
+		"owner": schema.BoolAttribute{
+			Required:    true,
+			Description: "Grant owner permission",
+		},
+	}
+}
+
+// UpgradeState reads the state written by the SDKv2 garage_bucket_key. Its attributes are unchanged,
+// only the ID is normalised.
+func (r *garageBucketKeyResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	priorSchema := schema.Schema{Attributes: garageBucketKeyAttributes()}
+
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &priorSchema,
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var data garageBucketKeyModel
+
+				resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				data.ID = types.StringValue(bucketKeyID(data.BucketID.ValueString(), data.AccessKeyID.ValueString()))
+
+				resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 			},
 		},
 	}
 }
 
-func resourceGarageBucketKeyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*GarageClient)
-	bucketID := d.Get("bucket_id").(string)
-	keyID := d.Get("access_key_id").(string)
-	read := d.Get("read").(bool)
-	write := d.Get("write").(bool)
-	owner := d.Get("owner").(bool)
+// ModifyPlan plans the ID, which changes with access_key_id
+func (r *garageBucketKeyResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// The resource is destroyed
+	if req.Plan.Raw.IsNull() {
+		return
+	}
 
-	perms := garage.NewApiBucketKeyPerm()
-	perms.SetRead(read)
-	perms.SetWrite(write)
-	perms.SetOwner(owner)
+	var data garageBucketKeyModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() || data.BucketID.IsUnknown() || data.AccessKeyID.IsUnknown() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("id"), bucketKeyID(data.BucketID.ValueString(), data.AccessKeyID.ValueString()))...)
+}
+
+func (r *garageBucketKeyResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GarageClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *GarageClient, got %T", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+// configured reports whether Configure received a client. It did not when the provider configuration
+// depends on values that are not known yet, as the framework provider only configures known settings.
+func (r *garageBucketKeyResource) configured(diags *diag.Diagnostics) bool {
+	if r.client == nil {
+		diags.AddError("Unconfigured provider", "The provider configuration is not known yet, so bucket key permissions cannot be managed")
+		return false
+	}
+
+	return true
+}
+
+// allowed reports whether the admin token may call the endpoints an operation needs
+func (r *garageBucketKeyResource) allowed(diags *diag.Diagnostics, feature string, endpoints ...string) bool {
+	if err := r.client.requireEndpoints(feature, endpoints...); err != nil {
+		diags.AddError("Insufficient admin token scope", err.Error())
+		return false
+	}
+
+	return true
+}
+
+func (r *garageBucketKeyResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	parts := strings.SplitN(req.ID, "/", 2)
+
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		resp.Diagnostics.AddError("Invalid import ID", fmt.Sprintf("unexpected format of ID (%s), expected bucket_id/access_key_id", req.ID))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), bucketKeyID(parts[0], parts[1]))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("bucket_id"), parts[0])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("access_key_id"), parts[1])...)
+}
+
+func (r *garageBucketKeyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.configured(&resp.Diagnostics) || !r.allowed(&resp.Diagnostics, "Granting bucket key permissions", "AllowBucketKey", "GetKeyInfo") {
+		return
+	}
 
-	updateReq := garage.NewBucketKeyPermChangeRequest(keyID, bucketID, *perms)
+	var data garageBucketKeyModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	_, resp, err := client.Client.PermissionAPI.AllowBucketKey(ctx).Body(*updateReq).Execute()
+	if err := r.allow(ctx, data); err != nil {
+		resp.Diagnostics.AddError("Failed to update bucket key permissions", err.Error())
+		return
+	}
+
+	data.ID = types.StringValue(bucketKeyID(data.BucketID.ValueString(), data.AccessKeyID.ValueString()))
+
+	found, err := r.read(ctx, &data)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to update bucket key permissions: %w", err))
+		resp.Diagnostics.AddError("Failed to read key", err.Error())
+		return
 	}
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			_ = resp.Body.Close()
-		}
-	}()
 
-	d.SetId(fmt.Sprintf("%s/%s", bucketID, keyID))
+	if !found {
+		resp.Diagnostics.AddError("Failed to read key", fmt.Sprintf("key %s has no permissions on bucket %s after granting them", data.AccessKeyID.ValueString(), data.BucketID.ValueString()))
+		return
+	}
 
-	return resourceGarageBucketKeyRead(ctx, d, m)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
-func resourceGarageBucketKeyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*GarageClient)
-	bucketID := d.Get("bucket_id").(string)
-	keyID := d.Get("access_key_id").(string)
+func (r *garageBucketKeyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if !r.configured(&resp.Diagnostics) || !r.allowed(&resp.Diagnostics, "Reading bucket key permissions", "GetKeyInfo") {
+		return
+	}
+
+	var data garageBucketKeyModel
 
-	// Get key to check bucket associations
-	key, resp, err := client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(keyID).Execute()
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	found, err := r.read(ctx, &data)
 	if err != nil {
-		if resp != nil && resp.StatusCode == http.StatusNotFound {
-			d.SetId("")
-			return nil
+		resp.Diagnostics.AddError("Failed to read key", err.Error())
+		return
+	}
+
+	// The key is gone or has no permissions on this bucket
+	if !found {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *garageBucketKeyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !r.configured(&resp.Diagnostics) || !r.allowed(&resp.Diagnostics, "Updating bucket key permissions", "AllowBucketKey", "DenyBucketKey", "GetKeyInfo") {
+		return
+	}
+
+	var data, state garageBucketKeyModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// AllowBucketKey only grants permissions. The ones turned off, or those of the old key, are removed
+	// afterwards, so that the key keeps the permissions it already had while the new ones are granted
+	if err := r.allow(ctx, data); err != nil {
+		resp.Diagnostics.AddError("Failed to update bucket key permissions", err.Error())
+		r.savePartialState(ctx, state, resp)
+
+		return
+	}
+
+	if data.AccessKeyID.Equal(state.AccessKeyID) {
+		if err := r.deny(ctx, data, state); err != nil {
+			resp.Diagnostics.AddError("Failed to remove bucket key permissions", err.Error())
+			r.savePartialState(ctx, state, resp)
+
+			return
 		}
+	} else if err := r.release(ctx, state); err != nil {
+		resp.Diagnostics.AddError("Failed to remove the bucket permissions of the old key", err.Error())
+		r.savePartialState(ctx, state, resp)
+
+		return
+	}
+
+	data.ID = types.StringValue(bucketKeyID(data.BucketID.ValueString(), data.AccessKeyID.ValueString()))
+
+	if _, err := r.read(ctx, &data); err != nil {
+		resp.Diagnostics.AddError("Failed to read key", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *garageBucketKeyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.configured(&resp.Diagnostics) || !r.allowed(&resp.Diagnostics, "Removing bucket key permissions", "DenyBucketKey") {
+		return
+	}
+
+	var data garageBucketKeyModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Remove all permissions by denying them
+	perms := garage.NewApiBucketKeyPerm()
+	perms.SetRead(false)
+	perms.SetWrite(false)
+	perms.SetOwner(false)
+
+	updateReq := garage.NewBucketKeyPermChangeRequest(data.AccessKeyID.ValueString(), data.BucketID.ValueString(), *perms)
 
-		return diag.FromErr(fmt.Errorf("failed to read key: %w", err))
+	_, httpResp, err := r.client.Client.PermissionAPI.DenyBucketKey(ctx).Body(*updateReq).Execute()
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to remove bucket key permissions", err.Error())
+		return
 	}
 	defer func() {
-		if resp != nil && resp.Body != nil {
-			_ = resp.Body.Close()
+		if httpResp != nil && httpResp.Body != nil {
+			_ = httpResp.Body.Close()
 		}
 	}()
+}
 
-	// Find this bucket in the key's bucket list
-	for _, bucket := range key.Buckets {
-		if bucket.Id == bucketID {
-			if bucket.Permissions.Read != nil {
-				if err := d.Set("read", *bucket.Permissions.Read); err != nil {
-					return diag.FromErr(err)
-				}
-			}
-
-			if bucket.Permissions.Write != nil {
-				if err := d.Set("write", *bucket.Permissions.Write); err != nil {
-					return diag.FromErr(err)
-				}
-			}
+// allow grants the permissions set in data. Permissions the key already has are kept, even if unset in data.
+func (r *garageBucketKeyResource) allow(ctx context.Context, data garageBucketKeyModel) error {
+	perms := garage.NewApiBucketKeyPerm()
+	perms.SetRead(data.Read.ValueBool())
+	perms.SetWrite(data.Write.ValueBool())
+	perms.SetOwner(data.Owner.ValueBool())
 
-			if bucket.Permissions.Owner != nil {
-				if err := d.Set("owner", *bucket.Permissions.Owner); err != nil {
-					return diag.FromErr(err)
-				}
-			}
+	updateReq := garage.NewBucketKeyPermChangeRequest(data.AccessKeyID.ValueString(), data.BucketID.ValueString(), *perms)
 
-			return nil
-		}
+	_, resp, err := r.client.Client.PermissionAPI.AllowBucketKey(ctx).Body(*updateReq).Execute()
+	if err != nil {
+		return err
 	}
-
-	// Key doesn't have permissions on this bucket
-	d.SetId("")
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
 
 	return nil
 }
 
-func resourceGarageBucketKeyUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*GarageClient)
-	bucketID := d.Get("bucket_id").(string)
-	keyID := d.Get("access_key_id").(string)
-	read := d.Get("read").(bool)
-	write := d.Get("write").(bool)
-	owner := d.Get("owner").(bool)
-
+// deny removes the permissions set in state but not in data
+func (r *garageBucketKeyResource) deny(ctx context.Context, data, state garageBucketKeyModel) error {
 	perms := garage.NewApiBucketKeyPerm()
-	perms.SetRead(read)
-	perms.SetWrite(write)
-	perms.SetOwner(owner)
+	perms.SetRead(state.Read.ValueBool() && !data.Read.ValueBool())
+	perms.SetWrite(state.Write.ValueBool() && !data.Write.ValueBool())
+	perms.SetOwner(state.Owner.ValueBool() && !data.Owner.ValueBool())
+
+	if !perms.GetRead() && !perms.GetWrite() && !perms.GetOwner() {
+		return nil
+	}
 
-	updateReq := garage.NewBucketKeyPermChangeRequest(keyID, bucketID, *perms)
+	updateReq := garage.NewBucketKeyPermChangeRequest(data.AccessKeyID.ValueString(), data.BucketID.ValueString(), *perms)
 
-	_, resp, err := client.Client.PermissionAPI.AllowBucketKey(ctx).Body(*updateReq).Execute()
+	_, resp, err := r.client.Client.PermissionAPI.DenyBucketKey(ctx).Body(*updateReq).Execute()
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to update bucket key permissions: %w", err))
+		return err
 	}
 	defer func() {
 		if resp != nil && resp.Body != nil {
@@ -177,25 +363,61 @@ func resourceGarageBucketKeyUpdate(ctx context.Context, d *schema.ResourceData,
 		}
 	}()
 
-	return resourceGarageBucketKeyRead(ctx, d, m)
+	return nil
 }
 
-func resourceGarageBucketKeyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*GarageClient)
-	bucketID := d.Get("bucket_id").(string)
-	keyID := d.Get("access_key_id").(string)
+// release removes the permissions of the key the resource granted them to before access_key_id changed.
+// A key that expires keeps them: garage_key gives a key it rotates with a grace period an expiration, and
+// clients still using it keep access to the bucket until then.
+func (r *garageBucketKeyResource) release(ctx context.Context, state garageBucketKeyModel) error {
+	key, resp, err := r.client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(state.AccessKeyID.ValueString()).Execute()
+	if err != nil {
+		// The key is gone, and its permissions with it
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
 
-	// Remove all permissions by denying them
-	perms := garage.NewApiBucketKeyPerm()
-	perms.SetRead(false)
-	perms.SetWrite(false)
-	perms.SetOwner(false)
+		return err
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	if expiration, ok := key.GetExpirationOk(); ok && expiration != nil {
+		return nil
+	}
 
-	updateReq := garage.NewBucketKeyPermChangeRequest(keyID, bucketID, *perms)
+	return r.deny(ctx, garageBucketKeyModel{
+		BucketID:    state.BucketID,
+		AccessKeyID: state.AccessKeyID,
+		Read:        types.BoolValue(false),
+		Write:       types.BoolValue(false),
+		Owner:       types.BoolValue(false),
+	}, state)
+}
+
+// savePartialState stores the permissions the key has after a failed update, which may have granted some
+// of the planned ones. The prior state is kept if they cannot be read.
+func (r *garageBucketKeyResource) savePartialState(ctx context.Context, state garageBucketKeyModel, resp *resource.UpdateResponse) {
+	found, err := r.read(ctx, &state)
+	if err != nil || !found {
+		return
+	}
 
-	_, resp, err := client.Client.PermissionAPI.DenyBucketKey(ctx).Body(*updateReq).Execute()
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// read sets the permissions the key has on the bucket, and reports whether it has any
+func (r *garageBucketKeyResource) read(ctx context.Context, data *garageBucketKeyModel) (bool, error) {
+	key, resp, err := r.client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(data.AccessKeyID.ValueString()).Execute()
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to remove bucket key permissions: %w", err))
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+
+		return false, err
 	}
 	defer func() {
 		if resp != nil && resp.Body != nil {
@@ -203,7 +425,30 @@ func resourceGarageBucketKeyDelete(ctx context.Context, d *schema.ResourceData,
 		}
 	}()
 
-	d.SetId("")
+	// Find this bucket in the key's bucket list
+	for _, bucket := range key.Buckets {
+		if bucket.Id != data.BucketID.ValueString() {
+			continue
+		}
+
+		if bucket.Permissions.Read != nil {
+			data.Read = types.BoolValue(*bucket.Permissions.Read)
+		}
 
-	return nil
+		if bucket.Permissions.Write != nil {
+			data.Write = types.BoolValue(*bucket.Permissions.Write)
+		}
+
+		if bucket.Permissions.Owner != nil {
+			data.Owner = types.BoolValue(*bucket.Permissions.Owner)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func bucketKeyID(bucketID, keyID string) string {
+	return fmt.Sprintf("%s/%s", bucketID, keyID)
 }
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceGarageBucketLifecycleConfiguration() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceGarageBucketLifecycleConfigurationCreate,
+		ReadContext:   resourceGarageBucketLifecycleConfigurationRead,
+		UpdateContext: resourceGarageBucketLifecycleConfigurationUpdate,
+		DeleteContext: resourceGarageBucketLifecycleConfigurationDelete,
+		CustomizeDiff: func(ctx context.Context, d *schema.ResourceDiff, meta any) error {
+			_, err := expandLifecycleRules(d.Get("rule").([]interface{}))
+			return err
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: schema.ImportStatePassthroughContext,
+		},
+		Schema: map[string]*schema.Schema{
+			"bucket_id": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The bucket ID",
+			},
+			"rule": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Description: "The lifecycle rules of the bucket. Rules not listed here are removed",
+				Elem:        lifecycleRuleResource(),
+			},
+		},
+	}
+}
+
+func resourceGarageBucketLifecycleConfigurationCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+	bucketID := d.Get("bucket_id").(string)
+
+	rules, err := expandLifecycleRules(d.Get("rule").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := setBucketLifecycleConfiguration(ctx, client, bucketID, rules); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to set lifecycle configuration: %w", err))
+	}
+
+	d.SetId(bucketID)
+
+	return resourceGarageBucketLifecycleConfigurationRead(ctx, d, m)
+}
+
+func resourceGarageBucketLifecycleConfigurationRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+	bucketID := d.Id()
+
+	exists, err := bucketExists(ctx, client, bucketID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if !exists {
+		d.SetId("")
+		return nil
+	}
+
+	rules, err := getBucketLifecycleConfiguration(ctx, client, bucketID)
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("failed to read lifecycle configuration: %w", err))
+	}
+
+	if len(rules) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	if err := d.Set("bucket_id", bucketID); err != nil {
+		return diag.FromErr(err)
+	}
+
+	var diags diag.Diagnostics
+
+	// The rule written by expiration_days on garage_bucket is removed by every apply of this resource
+	configured := expandRuleIDs(d.Get("rule").([]interface{}))
+	for _, rule := range rules {
+		if rule.ID == expirationDaysRuleID && !slices.Contains(configured, rule.ID) {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  "Lifecycle configuration managed twice",
+				Detail: fmt.Sprintf("Bucket %s has the %q rule created by the expiration_days attribute of garage_bucket. "+
+					"Remove expiration_days from the garage_bucket, as this resource overwrites the whole lifecycle configuration and removes the rule.", bucketID, rule.ID),
+			})
+		}
+	}
+
+	if err := d.Set("rule", flattenLifecycleRules(rules)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	return diags
+}
+
+func resourceGarageBucketLifecycleConfigurationUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+
+	rules, err := expandLifecycleRules(d.Get("rule").([]interface{}))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err := setBucketLifecycleConfiguration(ctx, client, d.Id(), rules); err != nil {
+		return diag.FromErr(fmt.Errorf("failed to update lifecycle configuration: %w", err))
+	}
+
+	return resourceGarageBucketLifecycleConfigurationRead(ctx, d, m)
+}
+
+func resourceGarageBucketLifecycleConfigurationDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	client := m.(*GarageClient)
+	bucketID := d.Id()
+
+	exists, err := bucketExists(ctx, client, bucketID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if exists {
+		if err := deleteBucketLifecycleConfiguration(ctx, client, bucketID); err != nil {
+			return diag.FromErr(fmt.Errorf("failed to remove lifecycle configuration: %w", err))
+		}
+	}
+
+	d.SetId("")
+
+	return nil
+}
+
+func expandRuleIDs(raw []interface{}) []string {
+	ids := make([]string, 0, len(raw))
+
+	for _, v := range raw {
+		if v != nil {
+			ids = append(ids, v.(map[string]interface{})["id"].(string))
+		}
+	}
+
+	return ids
+}
+
+// bucketExists reports whether a bucket with the given ID exists
+func bucketExists(ctx context.Context, client *GarageClient, bucketID string) (bool, error) {
+	if err := client.requireEndpoints("Looking up a bucket", "GetBucketInfo"); err != nil {
+		return false, err
+	}
+
+	_, resp, err := client.Client.BucketAPI.GetBucketInfo(ctx).Id(bucketID).Execute()
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to read bucket: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestGarageBucketUpgradeState(t *testing.T) {
+	ctx := context.Background()
+
+	server, err := providerserver.NewProtocol6WithError(NewFrameworkProvider())()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// State written by the SDKv2 resource for a bucket without quotas, website documents or local aliases in the configuration
+	resp, err := server.UpgradeResourceState(ctx, &tfprotov6.UpgradeResourceStateRequest{
+		TypeName: "garage_bucket",
+		Version:  0,
+		RawState: &tfprotov6.RawState{JSON: []byte(`{
+			"id": "b1",
+			"global_alias": "app",
+			"global_aliases": ["app"],
+			"local_aliases": [{"access_key_id": "GK1", "alias": "mine"}],
+			"bytes": 10,
+			"objects": 1,
+			"expiration_days": 0,
+			"max_size": 0,
+			"max_objects": 5,
+			"website_access_enabled": false,
+			"website_access_index_document": "",
+			"website_access_error_document": "",
+			"force_destroy": false,
+			"lifecycle_rule": [{
+				"id": "logs",
+				"enabled": true,
+				"prefix": "",
+				"object_size_greater_than": 0,
+				"object_size_less_than": 0,
+				"expiration_days": 3,
+				"expiration_date": "",
+				"abort_incomplete_multipart_upload_days": 0
+			}]
+		}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range resp.Diagnostics {
+		t.Fatalf("UpgradeResourceState() diagnostic: %s: %s", d.Summary, d.Detail)
+	}
+
+	upgraded, err := resp.UpgradedState.Unmarshal(schemas.ResourceSchemas["garage_bucket"].ValueType())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var attributes map[string]tftypes.Value
+	if err := upgraded.As(&attributes); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"expiration_days", "max_size", "website_access_index_document", "website_access_error_document"} {
+		if !attributes[name].IsNull() {
+			t.Errorf("%s = %v, want null", name, attributes[name])
+		}
+	}
+
+	for name, want := range map[string]tftypes.Value{
+		"id":           tftypes.NewValue(tftypes.String, "b1"),
+		"global_alias": tftypes.NewValue(tftypes.String, "app"),
+		"max_objects":  tftypes.NewValue(tftypes.Number, 5),
+	} {
+		if !attributes[name].Equal(want) {
+			t.Errorf("%s = %v, want %v", name, attributes[name], want)
+		}
+	}
+
+	var localAliases []tftypes.Value
+	if err := attributes["local_aliases"].As(&localAliases); err != nil || len(localAliases) != 0 {
+		t.Errorf("local_aliases = %v, want no aliases", attributes["local_aliases"])
+	}
+
+	var rules []tftypes.Value
+	if err := attributes["lifecycle_rule"].As(&rules); err != nil || len(rules) != 1 {
+		t.Fatalf("lifecycle_rule = %v, want one rule", attributes["lifecycle_rule"])
+	}
+
+	var rule map[string]tftypes.Value
+	if err := rules[0].As(&rule); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"prefix", "object_size_greater_than", "object_size_less_than", "expiration_date", "abort_incomplete_multipart_upload_days"} {
+		if !rule[name].IsNull() {
+			t.Errorf("lifecycle_rule.0.%s = %v, want null", name, rule[name])
+		}
+	}
+
+	if want := tftypes.NewValue(tftypes.Number, 3); !rule["expiration_days"].Equal(want) {
+		t.Errorf("lifecycle_rule.0.expiration_days = %v, want %v", rule["expiration_days"], want)
+	}
+}
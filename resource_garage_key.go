@@ -4,87 +4,673 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	"filippo.io/age"
 	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
-	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
-func resourceGarageKey() *schema.Resource {
-	return &schema.Resource{
-		CreateContext: resourceGarageKeyCreate,
-		ReadContext:   resourceGarageKeyRead,
-		UpdateContext: resourceGarageKeyUpdate,
-		DeleteContext: resourceGarageKeyDelete,
-		Importer:      &schema.ResourceImporter{},
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:        schema.TypeString,
+// garageKeyResource is served by the framework provider. Version 0 of its schema is the one of the
+// SDKv2 resource it replaces, whose state is upgraded by UpgradeState.
+type garageKeyResource struct {
+	client *GarageClient
+}
+
+var (
+	_ resource.ResourceWithConfigure      = &garageKeyResource{}
+	_ resource.ResourceWithImportState    = &garageKeyResource{}
+	_ resource.ResourceWithModifyPlan     = &garageKeyResource{}
+	_ resource.ResourceWithUpgradeState   = &garageKeyResource{}
+	_ resource.ResourceWithValidateConfig = &garageKeyResource{}
+)
+
+type garageKeyModel struct {
+	ID                               types.String `tfsdk:"id"`
+	Name                             types.String `tfsdk:"name"`
+	AccessKeyID                      types.String `tfsdk:"access_key_id"`
+	SecretAccessKey                  types.String `tfsdk:"secret_access_key"`
+	PGPKey                           types.String `tfsdk:"pgp_key"`
+	AgeRecipient                     types.String `tfsdk:"age_recipient"`
+	KeyFingerprint                   types.String `tfsdk:"key_fingerprint"`
+	EncryptedSecretAccessKey         types.String `tfsdk:"encrypted_secret_access_key"`
+	FetchSecretOnRead                types.Bool   `tfsdk:"fetch_secret_on_read"`
+	ImportAccessKeyID                types.String `tfsdk:"import_access_key_id"`
+	ImportSecretAccessKey            types.String `tfsdk:"import_secret_access_key"`
+	ImportSecretAccessKeySHA256      types.String `tfsdk:"import_secret_access_key_sha256"`
+	AllowCreateBucket                types.Bool   `tfsdk:"allow_create_bucket"`
+	Expiration                       types.String `tfsdk:"expiration"`
+	NeverExpires                     types.Bool   `tfsdk:"never_expires"`
+	Expired                          types.Bool   `tfsdk:"expired"`
+	Created                          types.String `tfsdk:"created"`
+	RotationTriggers                 types.Map    `tfsdk:"rotation_triggers"`
+	RotateAfter                      types.String `tfsdk:"rotate_after"`
+	RotationGracePeriod              types.String `tfsdk:"rotation_grace_period"`
+	PreviousAccessKeyID              types.String `tfsdk:"previous_access_key_id"`
+	PreviousSecretAccessKey          types.String `tfsdk:"previous_secret_access_key"`
+	EncryptedPreviousSecretAccessKey types.String `tfsdk:"encrypted_previous_secret_access_key"`
+	RotatedAt                        types.String `tfsdk:"rotated_at"`
+}
+
+func NewGarageKeyResource() resource.Resource {
+	return &garageKeyResource{}
+}
+
+func (r *garageKeyResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_key"
+}
+
+func (r *garageKeyResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = garageKeySchema()
+	resp.Schema.Version = 1
+}
+
+// garageKeySchema is the schema of garage_key. Version 0, written by the SDKv2 resource, has the same
+// attribute types, so it is also the prior schema of UpgradeState.
+func garageKeySchema() schema.Schema {
+	// Computed attributes only change when the key is rotated, its secret fetched or its expiration changed,
+	// as planned by ModifyPlan
+	computedString := func(description string, sensitive bool) schema.StringAttribute {
+		return schema.StringAttribute{
+			Computed:    true,
+			Sensitive:   sensitive,
+			Description: description,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		}
+	}
+
+	return schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": computedString("The access key ID", false),
+			"name": schema.StringAttribute{
 				Required:    true,
 				Description: "The name of the access key",
 			},
-			"access_key_id": {
-				Type:        schema.TypeString,
+			"access_key_id": computedString("The access key ID", false),
+			"secret_access_key": computedString("The secret access key. Only known after create, or after the first apply following terraform import, "+
+				"unless fetch_secret_on_read is set. Empty when pgp_key or age_recipient is set", true),
+			"pgp_key": schema.StringAttribute{
+				Optional:    true,
+				Description: "Encrypt the secret access key for this PGP public key instead of storing it in plaintext, given as keybase:username, an armored key or a base64 encoded key",
+			},
+			"age_recipient": schema.StringAttribute{
+				Optional:    true,
+				Description: "Encrypt the secret access key for this age recipient (age1...) instead of storing it in plaintext",
+			},
+			"key_fingerprint":             computedString("The fingerprint of the PGP key, or the age recipient, the secrets are encrypted for", false),
+			"encrypted_secret_access_key": computedString("The base64 encoded secret access key, encrypted for pgp_key or age_recipient", false),
+			"fetch_secret_on_read": schema.BoolAttribute{
+				Optional:    true,
 				Computed:    true,
-				Description: "The access key ID",
+				Default:     booldefault.StaticBool(false),
+				Description: "Read the secret access key from the cluster on every refresh, for example to recover it after losing state",
+			},
+			"import_access_key_id": schema.StringAttribute{
+				Optional:    true,
+				Description: "Import an existing access key ID, for example from another S3 service, instead of generating one. Changing it replaces the key",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
-			"secret_access_key": {
-				Type:        schema.TypeString,
+			"import_secret_access_key": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				WriteOnly: true,
+				Description: "The secret access key to import with import_access_key_id. It is write-only and not stored in state, but is exposed as " +
+					"secret_access_key. Changing it replaces the key. Requires Terraform 1.11 or later",
+			},
+			"import_secret_access_key_sha256": computedString("The SHA-256 hash of import_secret_access_key, used to detect changes of the write-only secret", false),
+			"allow_create_bucket": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Whether the key is allowed to create buckets",
+			},
+			"expiration": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "When the key stops working (RFC3339). Must be in the future when set or changed",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"never_expires": schema.BoolAttribute{
+				Optional:    true,
 				Computed:    true,
-				Sensitive:   true,
-				Description: "The secret access key (only available on create)",
+				Default:     booldefault.StaticBool(false),
+				Description: "Remove any expiration from the key, so that it stays valid until it is deleted",
+			},
+			"expired": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the key has expired",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"created": computedString("When the current key was created (RFC3339)", false),
+			"rotation_triggers": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Arbitrary values that rotate the key when they change. The current key becomes the previous key and a successor with the same bucket permissions replaces it",
+			},
+			"rotate_after": schema.StringAttribute{
+				Optional:    true,
+				Description: "Rotate the key on the first apply once it is older than this duration, such as 2160h for 90 days",
 			},
+			"rotation_grace_period": schema.StringAttribute{
+				Optional: true,
+				Description: "How long the previous key keeps working after a rotation. It expires at the end of the period and is deleted on the next apply. " +
+					"Without a grace period it is deleted on the apply following the rotation",
+			},
+			"previous_access_key_id":               computedString("The access key ID of the key replaced by the last rotation, until it is revoked", false),
+			"previous_secret_access_key":           computedString("The secret access key of the key replaced by the last rotation, until it is revoked", true),
+			"encrypted_previous_secret_access_key": computedString("The base64 encoded previous secret access key, encrypted for pgp_key or age_recipient", false),
+			"rotated_at":                           computedString("When the key was last rotated (RFC3339)", false),
 		},
 	}
 }
 
-func resourceGarageKeyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*GarageClient)
-	name := d.Get("name").(string)
+// UpgradeState reads the state written by the SDKv2 garage_key, which stored an empty string for unset
+// arguments where the framework resource stores null. Computed attributes keep their empty strings.
+func (r *garageKeyResource) UpgradeState(ctx context.Context) map[int64]resource.StateUpgrader {
+	priorSchema := garageKeySchema()
 
-	keyBody := garage.NewUpdateKeyRequestBody()
-	keyBody.SetName(name)
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &priorSchema,
+			StateUpgrader: func(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+				var data garageKeyModel
+
+				resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+				if resp.Diagnostics.HasError() {
+					return
+				}
+
+				data.PGPKey = stringOrNull(data.PGPKey.ValueString())
+				data.AgeRecipient = stringOrNull(data.AgeRecipient.ValueString())
+				data.ImportAccessKeyID = stringOrNull(data.ImportAccessKeyID.ValueString())
+				data.RotateAfter = stringOrNull(data.RotateAfter.ValueString())
+				data.RotationGracePeriod = stringOrNull(data.RotationGracePeriod.ValueString())
+
+				if len(data.RotationTriggers.Elements()) == 0 {
+					data.RotationTriggers = types.MapNull(types.StringType)
+				}
+
+				resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			},
+		},
+	}
+}
+
+func (r *garageKeyResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config garageKeyModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	conflicts := [][2]string{
+		{"pgp_key", "age_recipient"},
+		{"expiration", "never_expires"},
+		{"rotation_triggers", "import_access_key_id"},
+		{"rotate_after", "import_access_key_id"},
+	}
+
+	set := map[string]bool{
+		"pgp_key":                  !config.PGPKey.IsNull(),
+		"age_recipient":            !config.AgeRecipient.IsNull(),
+		"expiration":               !config.Expiration.IsNull(),
+		"never_expires":            !config.NeverExpires.IsNull(),
+		"rotation_triggers":        !config.RotationTriggers.IsNull(),
+		"rotate_after":             !config.RotateAfter.IsNull(),
+		"import_access_key_id":     !config.ImportAccessKeyID.IsNull(),
+		"import_secret_access_key": !config.ImportSecretAccessKey.IsNull(),
+	}
+
+	for _, names := range conflicts {
+		if set[names[0]] && set[names[1]] {
+			resp.Diagnostics.AddAttributeError(path.Root(names[0]), "Conflicting configuration", fmt.Sprintf("%s and %s cannot both be set", names[0], names[1]))
+		}
+	}
+
+	if set["import_access_key_id"] != set["import_secret_access_key"] {
+		resp.Diagnostics.AddAttributeError(path.Root("import_access_key_id"), "Incomplete configuration", "import_access_key_id and import_secret_access_key must be set together")
+	}
+
+	if v := config.AgeRecipient; !v.IsNull() && !v.IsUnknown() {
+		if _, err := age.ParseX25519Recipient(v.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("age_recipient"), "Invalid age_recipient",
+				fmt.Sprintf("age_recipient must be an age X25519 recipient such as age1...: %s", err))
+		}
+	}
+
+	if v := config.Expiration; !v.IsNull() && !v.IsUnknown() {
+		if _, err := time.Parse(time.RFC3339, v.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("expiration"), "Invalid expiration", fmt.Sprintf("expiration must be an RFC3339 timestamp: %s", err))
+		}
+	}
+
+	for name, v := range map[string]types.String{"rotate_after": config.RotateAfter, "rotation_grace_period": config.RotationGracePeriod} {
+		if v.IsNull() || v.IsUnknown() {
+			continue
+		}
+
+		if d, err := time.ParseDuration(v.ValueString()); err != nil || d < 0 {
+			resp.Diagnostics.AddAttributeError(path.Root(name), fmt.Sprintf("Invalid %s", name),
+				fmt.Sprintf("%s must be a positive duration such as 500ms or 10s, got %q", name, v.ValueString()))
+		}
+	}
+}
+
+// ModifyPlan replaces an imported key whose secret changed, plans rotations, marks the secrets unknown when
+// they are read again from the cluster, and rejects expirations in the past
+func (r *garageKeyResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// The resource is destroyed
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan, state garageKeyModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var importSecret types.String
+
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("import_secret_access_key"), &importSecret)...)
 
-	key, resp, err := client.Client.AccessKeyAPI.CreateKey(ctx).Body(*keyBody).Execute()
+		modifyImportedSecretPlan(&plan, state, importSecret, resp)
+		modifyRotationPlan(&plan, state, &resp.Diagnostics)
+
+		if keySecretFetchPlanned(keySecretChanges(plan, state)) {
+			plan.SecretAccessKey = types.StringUnknown()
+			plan.EncryptedSecretAccessKey = types.StringUnknown()
+			plan.PreviousSecretAccessKey = types.StringUnknown()
+			plan.EncryptedPreviousSecretAccessKey = types.StringUnknown()
+			plan.KeyFingerprint = types.StringUnknown()
+		}
+	}
+
+	modifyExpirationPlan(&plan, state, &resp.Diagnostics)
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *garageKeyResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GarageClient)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *GarageClient, got %T", req.ProviderData))
+		return
+	}
+
+	r.client = client
+}
+
+// configured reports whether Configure received a client. It did not when the provider configuration
+// depends on values that are not known yet, as the framework provider only configures known settings.
+func (r *garageKeyResource) configured(diags *diag.Diagnostics) bool {
+	if r.client == nil {
+		diags.AddError("Unconfigured provider", "The provider configuration is not known yet, so keys cannot be managed")
+		return false
+	}
+
+	return true
+}
+
+// allowed reports whether the admin token may call the endpoints an operation needs
+func (r *garageKeyResource) allowed(diags *diag.Diagnostics, feature string, endpoints ...string) bool {
+	if err := r.client.requireEndpoints(feature, endpoints...); err != nil {
+		diags.AddError("Insufficient admin token scope", err.Error())
+		return false
+	}
+
+	return true
+}
+
+// ImportState does not read the secret of the key: import has no configuration, so it could not be
+// encrypted for pgp_key or age_recipient. The first apply after the import stores it.
+func (r *garageKeyResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+func (r *garageKeyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if !r.configured(&resp.Diagnostics) {
+		return
+	}
+
+	var data garageKeyModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.ImportAccessKeyID.ValueString() != "" {
+		var secret types.String
+
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("import_secret_access_key"), &secret)...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		r.createFromImport(ctx, &data, secret, resp)
+
+		return
+	}
+
+	if !r.allowed(&resp.Diagnostics, "Creating a key", "CreateKey", "GetKeyInfo") {
+		return
+	}
+
+	if err := requireKeyExpiration(r.client, data); err != nil {
+		resp.Diagnostics.AddError("Unsupported Garage version", err.Error())
+		return
+	}
+
+	keyBody, _ := newKeyCreateBody(data)
+
+	key, httpResp, err := r.client.Client.AccessKeyAPI.CreateKey(ctx).Body(*keyBody).Execute()
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to create key: %w", err))
+		resp.Diagnostics.AddError("Failed to create key", err.Error())
+		return
 	}
 	defer func() {
-		if resp != nil && resp.Body != nil {
-			_ = resp.Body.Close()
+		if httpResp != nil && httpResp.Body != nil {
+			_ = httpResp.Body.Close()
 		}
 	}()
 
-	d.SetId(key.AccessKeyId)
+	// Keep the key in state, tainted, if reading it back fails
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), key.AccessKeyId)...)
+
+	data.ID = types.StringValue(key.AccessKeyId)
+	data.AccessKeyID = types.StringValue(key.AccessKeyId)
 
-	if err := d.Set("access_key_id", key.AccessKeyId); err != nil {
-		return diag.FromErr(err)
+	secret := ""
+	if s := key.SecretAccessKey.Get(); s != nil {
+		secret = *s
 	}
 
-	if key.SecretAccessKey.IsSet() {
-		secret := key.SecretAccessKey.Get()
-		if secret != nil {
-			if err := d.Set("secret_access_key", *secret); err != nil {
-				return diag.FromErr(err)
+	if err := setKeySecret(ctx, &data, &data.SecretAccessKey, &data.EncryptedSecretAccessKey, secret); err != nil {
+		resp.Diagnostics.AddError("Failed to store the secret of the key", err.Error())
+		return
+	}
+
+	r.readAndSave(ctx, &data, &resp.State, &resp.Diagnostics)
+}
+
+// createFromImport imports existing credentials instead of generating new ones
+func (r *garageKeyResource) createFromImport(ctx context.Context, data *garageKeyModel, secret types.String, resp *resource.CreateResponse) {
+	keyID := data.ImportAccessKeyID.ValueString()
+
+	if secret.IsNull() || secret.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(path.Root("import_secret_access_key"), "Missing secret", fmt.Sprintf("import_secret_access_key is required to import key %s", keyID))
+		return
+	}
+
+	// ImportKey is part of every version of the v2 admin API, only the token scope can rule it out
+	endpoints := []string{"ImportKey", "GetKeyInfo"}
+	if _, ok := newKeyCreateBody(*data); ok {
+		endpoints = append(endpoints, "UpdateKey")
+	}
+
+	if !r.allowed(&resp.Diagnostics, "Importing a key", endpoints...) {
+		return
+	}
+
+	if err := requireKeyExpiration(r.client, *data); err != nil {
+		resp.Diagnostics.AddError("Unsupported Garage version", err.Error())
+		return
+	}
+
+	request := garage.NewImportKeyRequest(keyID, secret.ValueString())
+	request.SetName(data.Name.ValueString())
+
+	key, httpResp, err := r.client.Client.AccessKeyAPI.ImportKey(ctx).ImportKeyRequest(*request).Execute()
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to import key", fmt.Sprintf("failed to import key %s: %s", keyID, err))
+		return
+	}
+	defer func() {
+		if httpResp != nil && httpResp.Body != nil {
+			_ = httpResp.Body.Close()
+		}
+	}()
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), key.AccessKeyId)...)
+
+	data.ID = types.StringValue(key.AccessKeyId)
+	data.AccessKeyID = types.StringValue(key.AccessKeyId)
+	data.ImportSecretAccessKeySHA256 = types.StringValue(sha256Hex([]byte(secret.ValueString())))
+
+	if err := setKeySecret(ctx, data, &data.SecretAccessKey, &data.EncryptedSecretAccessKey, secret.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to store the secret of the key", err.Error())
+		return
+	}
+
+	// ImportKey only takes a name, apply the other settings afterwards
+	if keyBody, ok := newKeyCreateBody(*data); ok {
+		_, updateResp, err := r.client.Client.AccessKeyAPI.UpdateKey(ctx).Id(key.AccessKeyId).UpdateKeyRequestBody(*keyBody).Execute()
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to update imported key", err.Error())
+			return
+		}
+
+		defer func() {
+			if updateResp != nil && updateResp.Body != nil {
+				_ = updateResp.Body.Close()
 			}
+		}()
+	}
+
+	r.readAndSave(ctx, data, &resp.State, &resp.Diagnostics)
+}
+
+func (r *garageKeyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	if !r.configured(&resp.Diagnostics) || !r.allowed(&resp.Diagnostics, "Reading a key", "GetKeyInfo") {
+		return
+	}
+
+	var data garageKeyModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	found, err := r.read(ctx, &data)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read key", err.Error())
+		return
+	}
+
+	if !found {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *garageKeyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	if !r.configured(&resp.Diagnostics) {
+		return
+	}
+
+	var data, state garageKeyModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	expirationChanged := keyExpirationChanged(data, state)
+	settingsChanged := !data.Name.Equal(state.Name) || !data.AllowCreateBucket.Equal(state.AllowCreateBucket) ||
+		expirationChanged || !data.NeverExpires.Equal(state.NeverExpires)
+
+	if settingsChanged && !r.allowed(&resp.Diagnostics, "Updating a key", "UpdateKey") {
+		return
+	}
+
+	if expirationChanged || !data.NeverExpires.Equal(state.NeverExpires) {
+		if err := requireKeyExpiration(r.client, data); err != nil {
+			resp.Diagnostics.AddError("Unsupported Garage version", err.Error())
+			return
 		}
 	}
 
-	return nil
+	// A planned rotation is the only change to access_key_id. The successor key is updated below like the
+	// current one would have been, for the other changes of the same plan.
+	if data.AccessKeyID.IsUnknown() {
+		if err := rotateGarageKey(ctx, r.client, &data, state); err != nil {
+			resp.Diagnostics.AddError("Failed to rotate key", err.Error())
+			return
+		}
+
+		// The rotation created and deleted keys, keep track of them even if the rest of the update fails
+		defer func() {
+			if resp.Diagnostics.HasError() {
+				resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), data.ID)...)
+				resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("access_key_id"), data.AccessKeyID)...)
+				resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("previous_access_key_id"), data.PreviousAccessKeyID)...)
+			}
+		}()
+	}
+
+	// The grace period of the previous key is over
+	if previousID := state.PreviousAccessKeyID.ValueString(); previousID != "" && data.PreviousAccessKeyID.ValueString() == "" && !data.PreviousAccessKeyID.IsUnknown() {
+		if err := deleteGarageKey(ctx, r.client, previousID); err != nil {
+			resp.Diagnostics.AddError("Failed to revoke the previous key", err.Error())
+			return
+		}
+	}
+
+	keyID := data.ID.ValueString()
+
+	if keySecretFetchPlanned(keySecretChanges(data, state)) {
+		if err := reencryptKeySecrets(ctx, r.client, &data); err != nil {
+			resp.Diagnostics.AddError("Failed to read the secrets of the key", err.Error())
+			return
+		}
+	}
+
+	// Changes are applied in place so the access key ID and secret stay the same
+	if settingsChanged {
+		keyBody := garage.NewUpdateKeyRequestBody()
+		keyBody.SetName(data.Name.ValueString())
+
+		if !data.AllowCreateBucket.Equal(state.AllowCreateBucket) {
+			setKeyCreateBucketPermission(keyBody, data.AllowCreateBucket.ValueBool())
+		}
+
+		if expiration := data.Expiration.ValueString(); expirationChanged && expiration != "" {
+			// Validated by ValidateConfig
+			t, _ := time.Parse(time.RFC3339, expiration)
+			keyBody.SetExpiration(t)
+		}
+
+		if data.NeverExpires.ValueBool() {
+			keyBody.SetNeverExpires(true)
+		}
+
+		_, httpResp, err := r.client.Client.AccessKeyAPI.UpdateKey(ctx).Id(keyID).UpdateKeyRequestBody(*keyBody).Execute()
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to update key", err.Error())
+			return
+		}
+
+		defer func() {
+			if httpResp != nil && httpResp.Body != nil {
+				_ = httpResp.Body.Close()
+			}
+		}()
+	}
+
+	r.readAndSave(ctx, &data, &resp.State, &resp.Diagnostics)
 }
 
-func resourceGarageKeyRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	client := m.(*GarageClient)
-	keyID := d.Id()
+func (r *garageKeyResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if !r.configured(&resp.Diagnostics) || !r.allowed(&resp.Diagnostics, "Deleting a key", "DeleteKey") {
+		return
+	}
+
+	var data garageKeyModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	key, resp, err := client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(keyID).Execute()
+	if previousID := data.PreviousAccessKeyID.ValueString(); previousID != "" {
+		if err := deleteGarageKey(ctx, r.client, previousID); err != nil {
+			resp.Diagnostics.AddError("Failed to delete the previous key", err.Error())
+			return
+		}
+	}
+
+	if err := deleteGarageKey(ctx, r.client, data.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete key", err.Error())
+	}
+}
+
+// readAndSave reads the key after it was created or updated, and saves it in state
+func (r *garageKeyResource) readAndSave(ctx context.Context, data *garageKeyModel, state *tfsdk.State, diags *diag.Diagnostics) {
+	found, err := r.read(ctx, data)
+	if err == nil && !found {
+		err = fmt.Errorf("key %s not found", data.ID.ValueString())
+	}
+
+	if err != nil {
+		diags.AddError("Failed to read key", err.Error())
+		return
+	}
+
+	diags.Append(state.Set(ctx, data)...)
+}
+
+// read refreshes data from the cluster, and reports whether the key still exists
+func (r *garageKeyResource) read(ctx context.Context, data *garageKeyModel) (bool, error) {
+	fetchSecret := data.FetchSecretOnRead.ValueBool()
+
+	key, resp, err := r.client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(data.ID.ValueString()).ShowSecretKey(fetchSecret).Execute()
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
-			d.SetId("")
-			return nil
+			return false, nil
 		}
 
-		return diag.FromErr(fmt.Errorf("failed to read key: %w", err))
+		return false, err
 	}
 	defer func() {
 		if resp != nil && resp.Body != nil {
@@ -92,36 +678,228 @@ func resourceGarageKeyRead(ctx context.Context, d *schema.ResourceData, m interf
 		}
 	}()
 
-	if err := d.Set("access_key_id", key.AccessKeyId); err != nil {
-		return diag.FromErr(err)
+	data.AccessKeyID = types.StringValue(key.AccessKeyId)
+	data.Name = types.StringValue(key.Name)
+	data.AllowCreateBucket = types.BoolValue(key.Permissions.GetCreateBucket())
+	// fetch_secret_on_read only lives in state, default it so imports don't show a diff
+	data.FetchSecretOnRead = types.BoolValue(fetchSecret)
+
+	// Keep the configured format of the expiration if it is the same point in time
+	expiration := formatOptionalTime(key.GetExpirationOk())
+	if current := data.Expiration.ValueString(); expiration != "" && timesEqual(current, expiration) {
+		expiration = current
 	}
 
-	if err := d.Set("name", key.Name); err != nil {
-		return diag.FromErr(err)
+	data.Expiration = types.StringValue(expiration)
+	// never_expires drifts when an expiration was added to the key outside Terraform
+	data.NeverExpires = types.BoolValue(data.NeverExpires.ValueBool() && expiration == "")
+	data.Expired = types.BoolValue(key.Expired)
+	data.Created = types.StringValue(formatOptionalTime(key.GetCreatedOk()))
+
+	// An encrypted secret is kept as is, encrypting it again would change the state on every refresh
+	if secret := key.SecretAccessKey.Get(); fetchSecret && secret != nil && data.EncryptedSecretAccessKey.ValueString() == "" {
+		if err := setKeySecret(ctx, data, &data.SecretAccessKey, &data.EncryptedSecretAccessKey, *secret); err != nil {
+			return false, err
+		}
 	}
-	// Note: secret_access_key is not available on read, only on create
 
-	return nil
+	// Computed attributes not set yet, as after terraform import or when a key is created, are empty
+	for _, attribute := range []*types.String{
+		&data.SecretAccessKey, &data.KeyFingerprint, &data.EncryptedSecretAccessKey, &data.ImportSecretAccessKeySHA256,
+		&data.PreviousAccessKeyID, &data.PreviousSecretAccessKey, &data.EncryptedPreviousSecretAccessKey, &data.RotatedAt,
+	} {
+		if attribute.IsNull() || attribute.IsUnknown() {
+			*attribute = types.StringValue("")
+		}
+	}
+
+	return true, nil
 }
 
-func resourceGarageKeyUpdate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	// Garage doesn't support updating key names, so we recreate if name changes
-	if d.HasChange("name") {
-		// Delete and recreate
-		diags := resourceGarageKeyDelete(ctx, d, m)
-		if diags.HasError() {
-			return diags
+// setKeyCreateBucketPermission grants or revokes the permission to create buckets in a key update
+func setKeyCreateBucketPermission(keyBody *garage.UpdateKeyRequestBody, allow bool) {
+	perm := garage.NewKeyPerm()
+	perm.SetCreateBucket(true)
+
+	if allow {
+		keyBody.SetAllow(*perm)
+	} else {
+		keyBody.SetDeny(*perm)
+	}
+}
+
+// reencryptKeySecrets reads the secrets of the current and previous keys from the cluster and stores them
+// again, encrypted for the new pgp_key or age_recipient, or in plaintext if encryption was turned off
+func reencryptKeySecrets(ctx context.Context, client *GarageClient, data *garageKeyModel) error {
+	keys := map[string][2]*types.String{
+		data.ID.ValueString(): {&data.SecretAccessKey, &data.EncryptedSecretAccessKey},
+	}
+
+	// The previous key of this apply: the current key if it was just rotated, none if it was just revoked
+	if previousID := data.PreviousAccessKeyID.ValueString(); previousID != "" {
+		keys[previousID] = [2]*types.String{&data.PreviousSecretAccessKey, &data.EncryptedPreviousSecretAccessKey}
+	}
+
+	data.KeyFingerprint = types.StringValue("")
+
+	for keyID, attributes := range keys {
+		key, resp, err := client.Client.AccessKeyAPI.GetKeyInfo(ctx).Id(keyID).ShowSecretKey(true).Execute()
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
 		}
 
-		return resourceGarageKeyCreate(ctx, d, m)
+		if err != nil {
+			return fmt.Errorf("failed to read the secret of key %s: %w", keyID, err)
+		}
+
+		secret := ""
+		if s := key.SecretAccessKey.Get(); s != nil {
+			secret = *s
+		}
+
+		if err := setKeySecret(ctx, data, attributes[0], attributes[1], secret); err != nil {
+			return err
+		}
+	}
+
+	// Previous secrets planned unknown without a previous key
+	for _, attribute := range []*types.String{&data.PreviousSecretAccessKey, &data.EncryptedPreviousSecretAccessKey} {
+		if attribute.IsUnknown() {
+			*attribute = types.StringValue("")
+		}
 	}
 
 	return nil
 }
 
-func resourceGarageKeyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
-	// Note: Garage API doesn't have a delete key endpoint in v1
-	// We'll just remove from state
-	d.SetId("")
-	return nil
+// requireKeyExpiration checks that the cluster supports key expiration when it is configured
+func requireKeyExpiration(client *GarageClient, data garageKeyModel) error {
+	if data.Expiration.ValueString() == "" && !data.NeverExpires.ValueBool() {
+		return nil
+	}
+
+	return client.requireGarageVersion("Setting expiration or never_expires on a key", "2.0.0")
+}
+
+// newKeyCreateBody returns the settings of a new key, and whether any besides the name is set
+func newKeyCreateBody(data garageKeyModel) (*garage.UpdateKeyRequestBody, bool) {
+	keyBody := garage.NewUpdateKeyRequestBody()
+	keyBody.SetName(data.Name.ValueString())
+
+	settings := false
+
+	if data.AllowCreateBucket.ValueBool() {
+		setKeyCreateBucketPermission(keyBody, true)
+
+		settings = true
+	}
+
+	if expiration := data.Expiration.ValueString(); expiration != "" {
+		// Validated by ValidateConfig
+		t, _ := time.Parse(time.RFC3339, expiration)
+		keyBody.SetExpiration(t)
+
+		settings = true
+	}
+
+	return keyBody, settings
+}
+
+// keySecretChanges returns the arguments of keySecretFetchPlanned for a planned change
+func keySecretChanges(plan, state garageKeyModel) (encryptionChanged, secretMissing, fetchSecretOnRead, fetchSecretOnReadChanged bool) {
+	return !plan.PGPKey.Equal(state.PGPKey) || !plan.AgeRecipient.Equal(state.AgeRecipient),
+		keySecretMissing(state.SecretAccessKey.ValueString(), state.EncryptedSecretAccessKey.ValueString()),
+		plan.FetchSecretOnRead.ValueBool(),
+		!plan.FetchSecretOnRead.Equal(state.FetchSecretOnRead)
+}
+
+// keySecretMissing reports whether the secret of the key is not in state, as after terraform import
+func keySecretMissing(secret, encryptedSecret string) bool {
+	return secret == "" && encryptedSecret == ""
+}
+
+// keySecretFetchPlanned reports whether an update reads the secret of the key from the cluster. A secret in
+// state is read again to encrypt it for a new pgp_key or age_recipient. A missing one, as after terraform
+// import, is only read when fetch_secret_on_read is turned on, or changes encryption while it is on, so
+// that the plan converges even if the cluster does not return the secret.
+func keySecretFetchPlanned(encryptionChanged, secretMissing, fetchSecretOnRead, fetchSecretOnReadChanged bool) bool {
+	if secretMissing {
+		return fetchSecretOnRead && (fetchSecretOnReadChanged || encryptionChanged)
+	}
+
+	return encryptionChanged
+}
+
+// modifyImportedSecretPlan replaces an imported key when import_secret_access_key changes. The attribute
+// is write-only, so its hash is compared with the one stored at import, which works whether or not the
+// secret is encrypted. States written before the hash was stored fall back to the plaintext secret.
+func modifyImportedSecretPlan(plan *garageKeyModel, state garageKeyModel, secret types.String, resp *resource.ModifyPlanResponse) {
+	if state.ImportAccessKeyID.ValueString() == "" || secret.IsNull() || secret.IsUnknown() {
+		return
+	}
+
+	hash := sha256Hex([]byte(secret.ValueString()))
+
+	if current := state.ImportSecretAccessKeySHA256.ValueString(); current != "" {
+		if current != hash {
+			plan.ImportSecretAccessKeySHA256 = types.StringValue(hash)
+			resp.RequiresReplace = append(resp.RequiresReplace, path.Root("import_secret_access_key_sha256"))
+		}
+
+		return
+	}
+
+	if current := state.SecretAccessKey.ValueString(); current != "" && current != secret.ValueString() {
+		plan.SecretAccessKey = types.StringUnknown()
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("secret_access_key"))
+	}
+}
+
+// modifyExpirationPlan rejects expirations in the past when they are set or changed,
+// so that an existing key reaching its expiration does not fail every plan
+func modifyExpirationPlan(plan *garageKeyModel, state garageKeyModel, diags *diag.Diagnostics) {
+	// never_expires removes the expiration kept in state from an earlier configuration
+	if plan.NeverExpires.ValueBool() && plan.Expiration.ValueString() != "" {
+		plan.Expiration = types.StringValue("")
+	}
+
+	if plan.Expiration.IsUnknown() || !keyExpirationChanged(*plan, state) {
+		return
+	}
+
+	plan.Expired = types.BoolUnknown()
+
+	expiration := plan.Expiration.ValueString()
+	if expiration == "" {
+		return
+	}
+
+	t, err := time.Parse(time.RFC3339, expiration)
+	if err != nil {
+		diags.AddAttributeError(path.Root("expiration"), "Invalid expiration", fmt.Sprintf("invalid expiration %q: %s", expiration, err))
+		return
+	}
+
+	if !t.After(time.Now()) {
+		diags.AddAttributeError(path.Root("expiration"), "Expiration in the past", fmt.Sprintf("expiration %s is in the past, the key would never be usable", expiration))
+	}
+}
+
+// keyExpirationChanged reports whether the planned expiration is another point in time than the one in state
+func keyExpirationChanged(plan, state garageKeyModel) bool {
+	return !plan.Expiration.Equal(state.Expiration) && !timesEqual(plan.Expiration.ValueString(), state.Expiration.ValueString())
+}
+
+func timesEqual(a, b string) bool {
+	ta, err := time.Parse(time.RFC3339, a)
+	if err != nil {
+		return false
+	}
+
+	tb, err := time.Parse(time.RFC3339, b)
+	if err != nil {
+		return false
+	}
+
+	return ta.Equal(tb)
 }
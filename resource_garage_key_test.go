@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestKeySecretMissing(t *testing.T) {
+	tests := []struct {
+		name            string
+		secret          string
+		encryptedSecret string
+		want            bool
+	}{
+		{name: "imported", want: true},
+		{name: "plaintext", secret: "secret", want: false},
+		{name: "encrypted", encryptedSecret: "d2NGVm", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keySecretMissing(tt.secret, tt.encryptedSecret); got != tt.want {
+				t.Errorf("keySecretMissing() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeySecretFetchPlanned(t *testing.T) {
+	tests := []struct {
+		name                     string
+		encryptionChanged        bool
+		secretMissing            bool
+		fetchSecretOnRead        bool
+		fetchSecretOnReadChanged bool
+		want                     bool
+	}{
+		{name: "no change", want: false},
+		{name: "encryption changed", encryptionChanged: true, want: true},
+		{name: "imported", secretMissing: true, want: false},
+		{name: "imported with encryption", secretMissing: true, encryptionChanged: true, want: false},
+		{name: "imported, fetch turned on", secretMissing: true, fetchSecretOnRead: true, fetchSecretOnReadChanged: true, want: true},
+		{name: "imported, fetch on and encryption changed", secretMissing: true, fetchSecretOnRead: true, encryptionChanged: true, want: true},
+		{name: "fetch on but nothing returned", secretMissing: true, fetchSecretOnRead: true, want: false},
+		{name: "fetch turned off", secretMissing: true, fetchSecretOnReadChanged: true, want: false},
+		{name: "fetch turned on with a secret", fetchSecretOnRead: true, fetchSecretOnReadChanged: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keySecretFetchPlanned(tt.encryptionChanged, tt.secretMissing, tt.fetchSecretOnRead, tt.fetchSecretOnReadChanged)
+			if got != tt.want {
+				t.Errorf("keySecretFetchPlanned() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGarageKeyPlanAfterImport(t *testing.T) {
+	imported := map[string]tftypes.Value{
+		"id":                   tftypes.NewValue(tftypes.String, "GK1"),
+		"name":                 tftypes.NewValue(tftypes.String, "app"),
+		"access_key_id":        tftypes.NewValue(tftypes.String, "GK1"),
+		"secret_access_key":    tftypes.NewValue(tftypes.String, ""),
+		"fetch_secret_on_read": tftypes.NewValue(tftypes.Bool, false),
+		"allow_create_bucket":  tftypes.NewValue(tftypes.Bool, false),
+		"never_expires":        tftypes.NewValue(tftypes.Bool, false),
+	}
+
+	tests := []struct {
+		name       string
+		config     map[string]tftypes.Value
+		wantChange bool
+	}{
+		{
+			name:       "without fetch_secret_on_read",
+			config:     map[string]tftypes.Value{"name": tftypes.NewValue(tftypes.String, "app")},
+			wantChange: false,
+		},
+		{
+			name: "with fetch_secret_on_read",
+			config: map[string]tftypes.Value{
+				"name":                 tftypes.NewValue(tftypes.String, "app"),
+				"fetch_secret_on_read": tftypes.NewValue(tftypes.Bool, true),
+			},
+			wantChange: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned := planGarageKey(t, imported, tt.config)
+
+			var attributes map[string]tftypes.Value
+			if err := planned.As(&attributes); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := !attributes["secret_access_key"].IsKnown(); got != tt.wantChange {
+				t.Errorf("secret_access_key unknown = %t, want %t", got, tt.wantChange)
+			}
+
+			if got := !planned.Equal(objectValue(planned.Type().(tftypes.Object), imported)); got != tt.wantChange {
+				t.Errorf("planned change = %t, want %t: %s", got, tt.wantChange, planned)
+			}
+		})
+	}
+}
+
+// planGarageKey plans a garage_key with the given prior state and configuration, and returns the planned state
+func planGarageKey(t *testing.T, prior, config map[string]tftypes.Value) tftypes.Value {
+	t.Helper()
+
+	resp, ty := planGarageKeyResponse(t, prior, config)
+
+	for _, d := range resp.Diagnostics {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("PlanResourceChange() error: %s: %s", d.Summary, d.Detail)
+		}
+	}
+
+	planned, err := resp.PlannedState.Unmarshal(ty)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return planned
+}
+
+func planGarageKeyResponse(t *testing.T, prior, config map[string]tftypes.Value) (*tfprotov6.PlanResourceChangeResponse, tftypes.Object) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	server, err := providerserver.NewProtocol6WithError(NewFrameworkProvider())()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ty := schemas.ResourceSchemas["garage_key"].ValueType().(tftypes.Object)
+
+	proposed := make(map[string]tftypes.Value, len(prior)+len(config))
+	for name, v := range prior {
+		proposed[name] = v
+	}
+
+	for name, v := range config {
+		proposed[name] = v
+	}
+
+	resp, err := server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         "garage_key",
+		PriorState:       dynamicValue(t, ty, prior),
+		ProposedNewState: dynamicValue(t, ty, proposed),
+		Config:           dynamicValue(t, ty, config),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp, ty
+}
+
+// objectValue returns values as an object of type ty, with the attributes missing from values set to null
+func objectValue(ty tftypes.Object, values map[string]tftypes.Value) tftypes.Value {
+	attributes := make(map[string]tftypes.Value, len(ty.AttributeTypes))
+	for name, attributeType := range ty.AttributeTypes {
+		if v, ok := values[name]; ok {
+			attributes[name] = v
+		} else {
+			attributes[name] = tftypes.NewValue(attributeType, nil)
+		}
+	}
+
+	return tftypes.NewValue(ty, attributes)
+}
+
+// dynamicValue encodes objectValue(ty, values) for the provider server
+func dynamicValue(t *testing.T, ty tftypes.Object, values map[string]tftypes.Value) *tfprotov6.DynamicValue {
+	t.Helper()
+
+	v, err := tfprotov6.NewDynamicValue(ty, objectValue(ty, values))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &v
+}
+
+func TestGarageKeyUpgradeState(t *testing.T) {
+	ctx := context.Background()
+
+	server, err := providerserver.NewProtocol6WithError(NewFrameworkProvider())()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// State written by the SDKv2 resource for a key without encryption, rotation or import in the configuration
+	resp, err := server.UpgradeResourceState(ctx, &tfprotov6.UpgradeResourceStateRequest{
+		TypeName: "garage_key",
+		Version:  0,
+		RawState: &tfprotov6.RawState{JSON: []byte(`{
+			"id": "GK1",
+			"name": "app",
+			"access_key_id": "GK1",
+			"secret_access_key": "secret",
+			"pgp_key": "",
+			"age_recipient": "",
+			"key_fingerprint": "",
+			"encrypted_secret_access_key": "",
+			"fetch_secret_on_read": false,
+			"import_access_key_id": "",
+			"import_secret_access_key_sha256": "",
+			"allow_create_bucket": true,
+			"expiration": "",
+			"never_expires": false,
+			"expired": false,
+			"created": "2026-01-01T00:00:00Z",
+			"rotation_triggers": {},
+			"rotate_after": "",
+			"rotation_grace_period": "",
+			"previous_access_key_id": "",
+			"previous_secret_access_key": "",
+			"encrypted_previous_secret_access_key": "",
+			"rotated_at": ""
+		}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range resp.Diagnostics {
+		t.Fatalf("UpgradeResourceState() diagnostic: %s: %s", d.Summary, d.Detail)
+	}
+
+	upgraded, err := resp.UpgradedState.Unmarshal(schemas.ResourceSchemas["garage_key"].ValueType())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var attributes map[string]tftypes.Value
+	if err := upgraded.As(&attributes); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"pgp_key", "age_recipient", "import_access_key_id", "rotation_triggers", "rotate_after", "rotation_grace_period"} {
+		if !attributes[name].IsNull() {
+			t.Errorf("%s = %v, want null", name, attributes[name])
+		}
+	}
+
+	for name, want := range map[string]tftypes.Value{
+		"secret_access_key":      tftypes.NewValue(tftypes.String, "secret"),
+		"allow_create_bucket":    tftypes.NewValue(tftypes.Bool, true),
+		"expiration":             tftypes.NewValue(tftypes.String, ""),
+		"previous_access_key_id": tftypes.NewValue(tftypes.String, ""),
+	} {
+		if !attributes[name].Equal(want) {
+			t.Errorf("%s = %v, want %v", name, attributes[name], want)
+		}
+	}
+}
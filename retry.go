@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	defaultMaxRetries   = 5
+	defaultRetryMinWait = time.Second
+	defaultRetryMaxWait = 30 * time.Second
+)
+
+// idempotentAdminEndpoints are the admin API endpoints sent as POST that can safely be
+// repeated: applying them twice leaves the cluster in the same state as applying them once.
+var idempotentAdminEndpoints = map[string]bool{
+	"UpdateBucket":      true,
+	"UpdateKey":         true,
+	"AllowBucketKey":    true,
+	"DenyBucketKey":     true,
+	"AddBucketAlias":    true,
+	"RemoveBucketAlias": true,
+	"DeleteBucket":      true,
+	"DeleteKey":         true,
+}
+
+type dialRetriesKey struct{}
+
+// withoutDialRetries returns a context whose requests fail at once when the connection cannot be established
+func withoutDialRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dialRetriesKey{}, false)
+}
+
+// RetryConfig controls how transient API failures are retried.
+type RetryConfig struct {
+	MaxRetries int
+	MinWait    time.Duration
+	MaxWait    time.Duration
+}
+
+// retryTransport retries requests that failed because of a transient error, such as a
+// node restarting or a layout change in progress, with exponential backoff.
+type retryTransport struct {
+	next   http.RoundTripper
+	config RetryConfig
+}
+
+func newRetryTransport(next http.RoundTripper, config RetryConfig) *retryTransport {
+	return &retryTransport{next: next, config: config}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("cannot retry %s %s: request body cannot be replayed", req.Method, req.URL.Path)
+			}
+
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("cannot retry %s %s: %w", req.Method, req.URL.Path, err)
+			}
+
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := t.next.RoundTrip(attemptReq)
+
+		if attempt >= t.config.MaxRetries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+
+		fields := map[string]interface{}{
+			"method":  req.Method,
+			"url":     req.URL.Redacted(),
+			"attempt": attempt + 1,
+			"wait":    wait.String(),
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		} else {
+			fields["status"] = resp.StatusCode
+		}
+
+		tflog.Warn(ctx, "Retrying Garage API request after transient failure", fields)
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns how long to wait before the next attempt, honouring Retry-After
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(wait, t.config.MaxWait)
+		}
+	}
+
+	wait := t.config.MinWait << attempt
+	if wait <= 0 || wait > t.config.MaxWait {
+		return t.config.MaxWait
+	}
+
+	return wait
+}
+
+// shouldRetry reports whether a failed request is transient and safe to send again
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+
+		// Nothing reached the server if the connection could not be established, so even
+		// non-idempotent requests such as key or bucket creation are safe to retry
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return req.Context().Value(dialRetriesKey{}) == nil
+		}
+
+		return isIdempotent(req)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(req)
+	}
+
+	return false
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		// Admin API endpoints are served as /v2/<Endpoint>
+		return path.Base(path.Dir(req.URL.Path)) == "v2" && idempotentAdminEndpoints[path.Base(req.URL.Path)]
+	}
+
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
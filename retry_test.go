@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "empty", value: "", wantOK: false},
+		{name: "seconds", value: "120", want: 2 * time.Minute, wantOK: true},
+		{name: "zero seconds", value: "0", want: 0, wantOK: true},
+		{name: "negative seconds", value: "-1", wantOK: false},
+		{name: "past HTTP date", value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, wantOK: true},
+		{name: "invalid", value: "soon", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %s, %t, want %s, %t", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfterFutureDate(t *testing.T) {
+	value := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+
+	got, ok := parseRetryAfter(value)
+	if !ok || got <= 80*time.Second || got > 90*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %s, %t, want about 90s", value, got, ok)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	transport := newRetryTransport(nil, RetryConfig{MaxRetries: 5, MinWait: time.Second, MaxWait: 10 * time.Second})
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		want       time.Duration
+	}{
+		{name: "first attempt", attempt: 0, want: time.Second},
+		{name: "doubled", attempt: 2, want: 4 * time.Second},
+		{name: "capped", attempt: 4, want: 10 * time.Second},
+		{name: "overflow", attempt: 70, want: 10 * time.Second},
+		{name: "retry after", attempt: 0, retryAfter: "3", want: 3 * time.Second},
+		{name: "retry after capped", attempt: 0, retryAfter: "3600", want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			if got := transport.backoff(tt.attempt, resp); got != tt.want {
+				t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	resetErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		err    error
+		want   bool
+	}{
+		{name: "GET unavailable", method: http.MethodGet, path: "/v2/GetKeyInfo", status: http.StatusServiceUnavailable, want: true},
+		{name: "GET too many requests", method: http.MethodGet, path: "/v2/ListBuckets", status: http.StatusTooManyRequests, want: true},
+		{name: "GET not found", method: http.MethodGet, path: "/v2/GetKeyInfo", status: http.StatusNotFound, want: false},
+		{name: "GET bad request", method: http.MethodGet, path: "/v2/GetKeyInfo", status: http.StatusBadRequest, want: false},
+		{name: "PUT bad gateway", method: http.MethodPut, path: "/bucket?lifecycle", status: http.StatusBadGateway, want: true},
+		{name: "idempotent POST", method: http.MethodPost, path: "/v2/UpdateKey", status: http.StatusInternalServerError, want: true},
+		{name: "idempotent POST after reset", method: http.MethodPost, path: "/v2/AllowBucketKey", err: resetErr, want: true},
+		{name: "CreateKey", method: http.MethodPost, path: "/v2/CreateKey", status: http.StatusServiceUnavailable, want: false},
+		{name: "CreateBucket", method: http.MethodPost, path: "/v2/CreateBucket", status: http.StatusGatewayTimeout, want: false},
+		{name: "ImportKey", method: http.MethodPost, path: "/v2/ImportKey", status: http.StatusInternalServerError, want: false},
+		{name: "CreateKey after reset", method: http.MethodPost, path: "/v2/CreateKey", err: resetErr, want: false},
+		{name: "CreateKey after dial error", method: http.MethodPost, path: "/v2/CreateKey", err: dialErr, want: true},
+		{name: "POST outside the admin API", method: http.MethodPost, path: "/bucket/UpdateKey", status: http.StatusServiceUnavailable, want: false},
+		{name: "S3 DeleteObjects", method: http.MethodPost, path: "/bucket?delete", status: http.StatusServiceUnavailable, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://garage:3903"+tt.path, nil)
+
+			var resp *http.Response
+			if tt.err == nil {
+				resp = &http.Response{StatusCode: tt.status}
+			}
+
+			if got := shouldRetry(req, resp, tt.err); got != tt.want {
+				t.Errorf("shouldRetry(%s %s) = %t, want %t", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRetryCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "http://garage:3903/v2/GetKeyInfo", nil).WithContext(ctx)
+
+	if shouldRetry(req, nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")}) {
+		t.Error("shouldRetry() retried a cancelled request")
+	}
+}
+
+func TestShouldRetryWithoutDialRetries(t *testing.T) {
+	ctx := withoutDialRetries(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "http://garage:3903/v2/GetClusterHealth", nil).WithContext(ctx)
+
+	if shouldRetry(req, nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")}) {
+		t.Error("shouldRetry() retried a dial error with dial retries disabled")
+	}
+
+	if !shouldRetry(req, &http.Response{StatusCode: http.StatusServiceUnavailable}, nil) {
+		t.Error("shouldRetry() did not retry a transient status with dial retries disabled")
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		wantAttempts int32
+	}{
+		{name: "idempotent POST is retried", path: "/v2/UpdateKey", wantAttempts: 3},
+		{name: "CreateKey is not replayed", path: "/v2/CreateKey", wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: newRetryTransport(http.DefaultTransport, RetryConfig{
+				MaxRetries: 2,
+				MinWait:    time.Millisecond,
+				MaxWait:    time.Millisecond,
+			})}
+
+			resp, err := client.Post(server.URL+tt.path, "application/json", strings.NewReader(`{"name":"test"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+			}
+
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // Content-MD5 is mandated by the S3 DeleteObjects API
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+)
+
+// s3DeleteBatchSize is the maximum number of keys accepted by a single DeleteObjects call
+const s3DeleteBatchSize = 1000
+
+// ListBucketResult represents an S3 ListObjectsV2 response.
+type ListBucketResult struct {
+	XMLName               xml.Name   `xml:"ListBucketResult"`
+	Contents              []S3Object `xml:"Contents"`
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+}
+
+type S3Object struct {
+	Key string `xml:"Key"`
+}
+
+// DeleteObjects represents an S3 DeleteObjects request body.
+type DeleteObjects struct {
+	XMLName xml.Name   `xml:"Delete"`
+	Quiet   bool       `xml:"Quiet"`
+	Objects []S3Object `xml:"Object"`
+}
+
+// DeleteResult represents an S3 DeleteObjects response.
+type DeleteResult struct {
+	XMLName xml.Name        `xml:"DeleteResult"`
+	Errors  []DeleteError   `xml:"Error"`
+	Deleted []DeletedObject `xml:"Deleted"`
+}
+
+type DeleteError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+type DeletedObject struct {
+	Key string `xml:"Key"`
+}
+
+// ListMultipartUploadsResult represents an S3 ListMultipartUploads response.
+type ListMultipartUploadsResult struct {
+	XMLName            xml.Name          `xml:"ListMultipartUploadsResult"`
+	Uploads            []MultipartUpload `xml:"Upload"`
+	IsTruncated        bool              `xml:"IsTruncated"`
+	NextKeyMarker      string            `xml:"NextKeyMarker"`
+	NextUploadIDMarker string            `xml:"NextUploadIdMarker"`
+}
+
+type MultipartUpload struct {
+	Key      string `xml:"Key"`
+	UploadID string `xml:"UploadId"`
+}
+
+// s3BucketName returns the name under which a bucket is reachable on the S3 API. Garage only
+// addresses buckets by alias there, not by ID.
+func s3BucketName(bucket *garage.GetBucketInfoResponse) (string, error) {
+	if len(bucket.GlobalAliases) == 0 {
+		return "", fmt.Errorf("bucket %s has no global alias, which the S3 API requires to address it", bucket.Id)
+	}
+
+	return bucket.GlobalAliases[0], nil
+}
+
+// s3ResponseError builds an error from an unexpected S3 response, including the S3 error message if any
+func s3ResponseError(resp *http.Response) error {
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := xml.Unmarshal(data, &s3Err); err == nil && s3Err.Code != "" {
+		return fmt.Errorf("unexpected status code: %d (%s: %s)", resp.StatusCode, s3Err.Code, s3Err.Message)
+	}
+
+	return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+// emptyBucket deletes every object and aborts every incomplete multipart upload in a bucket
+func emptyBucket(ctx context.Context, s *s3Session) error {
+	if err := deleteAllObjects(ctx, s); err != nil {
+		return err
+	}
+
+	return abortAllMultipartUploads(ctx, s)
+}
+
+func deleteAllObjects(ctx context.Context, s *s3Session) error {
+	continuationToken := ""
+
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("max-keys", strconv.Itoa(s3DeleteBatchSize))
+
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		var result ListBucketResult
+		if err := s.getXML(ctx, query, &result); err != nil {
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		if len(result.Contents) > 0 {
+			if err := deleteObjects(ctx, s, result.Contents); err != nil {
+				return err
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+func deleteObjects(ctx context.Context, s *s3Session, objects []S3Object) error {
+	xmlData, err := xml.Marshal(DeleteObjects{Quiet: true, Objects: objects})
+	if err != nil {
+		return fmt.Errorf("failed to marshal delete request: %w", err)
+	}
+
+	sum := md5.Sum(xmlData) //nolint:gosec // required by the S3 API, not used for security
+	headers := map[string]string{
+		"Content-Type": "application/xml",
+		"Content-MD5":  base64.StdEncoding.EncodeToString(sum[:]),
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, "", url.Values{"delete": {""}}, xmlData, headers)
+	if err != nil {
+		return fmt.Errorf("failed to delete objects: %w", err)
+	}
+
+	defer func() {
+		if resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete objects: %w", s3ResponseError(resp))
+	}
+
+	var result DeleteResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode delete response: %w", err)
+	}
+
+	if len(result.Errors) > 0 {
+		first := result.Errors[0]
+
+		return fmt.Errorf("failed to delete %d objects, first error on %q: %s: %s", len(result.Errors), first.Key, first.Code, first.Message)
+	}
+
+	return nil
+}
+
+func abortAllMultipartUploads(ctx context.Context, s *s3Session) error {
+	keyMarker := ""
+	uploadIDMarker := ""
+
+	for {
+		query := url.Values{"uploads": {""}}
+		if keyMarker != "" {
+			query.Set("key-marker", keyMarker)
+		}
+
+		if uploadIDMarker != "" {
+			query.Set("upload-id-marker", uploadIDMarker)
+		}
+
+		var result ListMultipartUploadsResult
+		if err := s.getXML(ctx, query, &result); err != nil {
+			return fmt.Errorf("failed to list multipart uploads: %w", err)
+		}
+
+		for _, upload := range result.Uploads {
+			if err := abortMultipartUpload(ctx, s, upload); err != nil {
+				return err
+			}
+		}
+
+		if !result.IsTruncated {
+			return nil
+		}
+
+		keyMarker = result.NextKeyMarker
+		uploadIDMarker = result.NextUploadIDMarker
+	}
+}
+
+func abortMultipartUpload(ctx context.Context, s *s3Session, upload MultipartUpload) error {
+	resp, err := s.do(ctx, http.MethodDelete, upload.Key, url.Values{"uploadId": {upload.UploadID}}, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to abort multipart upload of %q: %w", upload.Key, err)
+	}
+
+	defer func() {
+		if resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to abort multipart upload of %q: %w", upload.Key, s3ResponseError(resp))
+	}
+
+	return nil
+}
+
+// getXML performs a GET on the session's bucket and decodes the XML response into out
+func (s *s3Session) getXML(ctx context.Context, query url.Values, out interface{}) error {
+	resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3ResponseError(resp)
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+)
+
+const (
+	defaultS3Region = "garage"
+
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	sigV4Service   = "s3"
+
+	// temporaryKeyLifetime bounds how long a temporary key stays valid should deleting it fail
+	temporaryKeyLifetime = time.Hour
+)
+
+// S3Client talks to the Garage S3 API, signing requests with AWS Signature Version 4.
+type S3Client struct {
+	HTTPClient *http.Client
+	Scheme     string
+	Host       string
+	// BasePath is prepended to every request path, for S3 APIs served under a path prefix
+	BasePath string
+	Region   string
+	// ForcePathStyle addresses buckets as host/bucket instead of bucket.host
+	ForcePathStyle  bool
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// s3Credentials is an access key pair used to sign S3 requests
+type s3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// s3Session is an authenticated handle on a single bucket of the S3 API
+type s3Session struct {
+	client *S3Client
+	creds  s3Credentials
+	bucket string
+}
+
+// hasCredentials reports whether static S3 credentials were configured on the provider
+func (c *S3Client) hasCredentials() bool {
+	return c.AccessKeyID != "" && c.SecretAccessKey != ""
+}
+
+// withS3Session runs fn with an S3 session on the given bucket. When the provider has no static
+// S3 credentials, a temporary key is created, granted access to the bucket and deleted afterwards.
+func (c *GarageClient) withS3Session(ctx context.Context, bucket *garage.GetBucketInfoResponse, fn func(s *s3Session) error) (err error) {
+	bucketName, err := s3BucketName(bucket)
+	if err != nil {
+		return err
+	}
+
+	session := &s3Session{client: c.S3, bucket: bucketName}
+
+	if c.S3.hasCredentials() {
+		session.creds = s3Credentials{AccessKeyID: c.S3.AccessKeyID, SecretAccessKey: c.S3.SecretAccessKey}
+
+		return fn(session)
+	}
+
+	if err := c.requireEndpoints("Accessing the S3 API without s3_endpoint credentials", "CreateKey", "AllowBucketKey", "DeleteKey"); err != nil {
+		return err
+	}
+
+	creds, err := c.createTemporaryKey(ctx, bucket.Id)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		resp, deleteErr := c.Client.AccessKeyAPI.DeleteKey(context.WithoutCancel(ctx)).Id(creds.AccessKeyID).Execute()
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+
+		if deleteErr != nil && err == nil {
+			err = fmt.Errorf("failed to delete temporary key %s: %w", creds.AccessKeyID, deleteErr)
+		}
+	}()
+
+	session.creds = creds
+
+	return fn(session)
+}
+
+// createTemporaryKey creates a short-lived key with full permissions on a bucket
+func (c *GarageClient) createTemporaryKey(ctx context.Context, bucketID string) (s3Credentials, error) {
+	keyBody := garage.NewUpdateKeyRequestBody()
+	keyBody.SetName("terraform-provider-garage-tmp")
+	keyBody.SetExpiration(time.Now().Add(temporaryKeyLifetime))
+
+	key, resp, err := c.Client.AccessKeyAPI.CreateKey(ctx).Body(*keyBody).Execute()
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+
+	if err != nil {
+		return s3Credentials{}, fmt.Errorf("failed to create temporary key: %w", err)
+	}
+
+	creds := s3Credentials{AccessKeyID: key.AccessKeyId}
+	if secret := key.SecretAccessKey.Get(); secret != nil {
+		creds.SecretAccessKey = *secret
+	}
+
+	perms := garage.NewApiBucketKeyPerm()
+	perms.SetRead(true)
+	perms.SetWrite(true)
+	perms.SetOwner(true)
+
+	_, resp, err = c.Client.PermissionAPI.AllowBucketKey(ctx).Body(*garage.NewBucketKeyPermChangeRequest(key.AccessKeyId, bucketID, *perms)).Execute()
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+
+	if err != nil {
+		resp, _ := c.Client.AccessKeyAPI.DeleteKey(context.WithoutCancel(ctx)).Id(key.AccessKeyId).Execute()
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+
+		return s3Credentials{}, fmt.Errorf("failed to grant temporary key access to bucket: %w", err)
+	}
+
+	return creds, nil
+}
+
+// do sends a signed request for the session's bucket and optional object key.
+// The caller is responsible for closing the response body.
+func (s *s3Session) do(ctx context.Context, method, key string, query url.Values, body []byte, headers map[string]string) (*http.Response, error) {
+	host := s.client.Host
+	path := s.client.BasePath
+
+	if s.client.ForcePathStyle {
+		path += "/" + s3EscapePath(s.bucket)
+	} else {
+		host = s.bucket + "." + host
+	}
+
+	if key != "" {
+		path += "/" + s3EscapePath(key)
+	}
+
+	if path == "" {
+		path = "/"
+	}
+
+	rawURL := fmt.Sprintf("%s://%s%s", s.client.Scheme, host, path)
+
+	canonicalQuery := s3CanonicalQuery(query)
+	if canonicalQuery != "" {
+		rawURL += "?" + canonicalQuery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	signS3Request(req, path, canonicalQuery, body, s.creds, s.client.Region, time.Now())
+
+	resp, err := s.client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+
+	return resp, nil
+}
+
+// signS3Request adds AWS Signature Version 4 headers to req
+func signS3Request(req *http.Request, canonicalURI, canonicalQuery string, body []byte, creds s3Credentials, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	shortDate := now.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalRequest, signedHeaders := s3CanonicalRequest(req, canonicalURI, canonicalQuery, payloadHash)
+
+	scope := strings.Join([]string{shortDate, region, sigV4Service, "aws4_request"}, "/")
+	signature := sigV4Signature(creds.SecretAccessKey, shortDate, region, sigV4Service, sigV4StringToSign(amzDate, scope, canonicalRequest))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// s3CanonicalRequest returns the canonical form of req and the list of headers it signs. The host,
+// content type and MD5 and x-amz-* headers are signed.
+func s3CanonicalRequest(req *http.Request, canonicalURI, canonicalQuery, payloadHash string) (string, string) {
+	headers := map[string]string{"host": req.URL.Host}
+
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" || lower == "content-md5" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	return canonicalRequest, signedHeaders
+}
+
+func sigV4StringToSign(amzDate, scope, canonicalRequest string) string {
+	return strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+}
+
+func sigV4Signature(secretAccessKey, shortDate, region, service, stringToSign string) string {
+	signingKey := hmacSHA256([]byte("AWS4"+secretAccessKey), shortDate)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+}
+
+// s3CanonicalQuery encodes query parameters as required by Signature Version 4
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+
+		for _, value := range values {
+			parts = append(parts, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// s3EscapePath URI-encodes a path, keeping the slashes that separate its segments
+func s3EscapePath(path string) string {
+	return s3Escape(path, false)
+}
+
+// s3Escape URI-encodes every byte except the unreserved characters, as specified by Signature Version 4
+func s3Escape(value string, encodeSlash bool) string {
+	var buf strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			buf.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+
+	return buf.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = io.WriteString(mac, data)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// secretEncryptor encrypts secrets for a PGP key or an age recipient before they are stored in state
+type secretEncryptor struct {
+	pgpEntity    *openpgp.Entity
+	ageRecipient *age.X25519Recipient
+}
+
+// newSecretEncryptor returns the encryptor configured by pgp_key or age_recipient, or nil if neither is set
+func newSecretEncryptor(ctx context.Context, pgpKey, ageRecipient string) (*secretEncryptor, error) {
+	if pgpKey != "" {
+		entity, err := loadPGPKey(ctx, pgpKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &secretEncryptor{pgpEntity: entity}, nil
+	}
+
+	if ageRecipient != "" {
+		r, err := age.ParseX25519Recipient(ageRecipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age_recipient: %w", err)
+		}
+
+		return &secretEncryptor{ageRecipient: r}, nil
+	}
+
+	return nil, nil
+}
+
+// fingerprint identifies the key secrets are encrypted for
+func (e *secretEncryptor) fingerprint() string {
+	if e.pgpEntity != nil {
+		return hex.EncodeToString(e.pgpEntity.PrimaryKey.Fingerprint)
+	}
+
+	return e.ageRecipient.String()
+}
+
+// encrypt returns the base64 encoded encrypted secret
+func (e *secretEncryptor) encrypt(secret string) (string, error) {
+	var buf bytes.Buffer
+
+	var (
+		w   io.WriteCloser
+		err error
+	)
+
+	if e.pgpEntity != nil {
+		w, err = openpgp.Encrypt(&buf, []*openpgp.Entity{e.pgpEntity}, nil, nil, nil)
+	} else {
+		w, err = age.Encrypt(&buf, e.ageRecipient)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	if _, err := w.Write([]byte(secret)); err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// setKeySecret stores a secret in attribute, or only its encrypted form in encryptedAttribute when
+// pgp_key or age_recipient is set
+func setKeySecret(ctx context.Context, data *garageKeyModel, attribute, encryptedAttribute *types.String, secret string) error {
+	encryptor, err := newSecretEncryptor(ctx, data.PGPKey.ValueString(), data.AgeRecipient.ValueString())
+	if err != nil {
+		return err
+	}
+
+	if encryptor == nil {
+		*encryptedAttribute = types.StringValue("")
+		*attribute = types.StringValue(secret)
+
+		return nil
+	}
+
+	encrypted := ""
+	if secret != "" {
+		if encrypted, err = encryptor.encrypt(secret); err != nil {
+			return err
+		}
+	}
+
+	data.KeyFingerprint = types.StringValue(encryptor.fingerprint())
+	*attribute = types.StringValue("")
+	*encryptedAttribute = types.StringValue(encrypted)
+
+	return nil
+}
+
+// loadPGPKey reads a PGP public key given as keybase:username, an armored key or a base64 encoded key
+func loadPGPKey(ctx context.Context, pgpKey string) (*openpgp.Entity, error) {
+	var (
+		entities openpgp.EntityList
+		err      error
+	)
+
+	switch {
+	case strings.HasPrefix(pgpKey, "keybase:"):
+		entities, err = fetchKeybasePGPKey(ctx, strings.TrimPrefix(pgpKey, "keybase:"))
+	case strings.Contains(pgpKey, "-----BEGIN PGP PUBLIC KEY BLOCK-----"):
+		entities, err = openpgp.ReadArmoredKeyRing(strings.NewReader(pgpKey))
+	default:
+		var raw []byte
+
+		raw, err = base64.StdEncoding.DecodeString(strings.TrimSpace(pgpKey))
+		if err != nil {
+			return nil, fmt.Errorf("pgp_key must be keybase:username, an armored public key or a base64 encoded public key: %w", err)
+		}
+
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(raw))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pgp_key: %w", err)
+	}
+
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("pgp_key contains no public key")
+	}
+
+	return entities[0], nil
+}
+
+func fetchKeybasePGPKey(ctx context.Context, username string) (openpgp.EntityList, error) {
+	keyURL := fmt.Sprintf("https://keybase.io/%s/pgp_keys.asc", url.PathEscape(username))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, keyURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the PGP key of keybase user %s: %w", username, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the PGP key of keybase user %s: %s", username, resp.Status)
+	}
+
+	return openpgp.ReadArmoredKeyRing(resp.Body)
+}